@@ -0,0 +1,125 @@
+// Copyright 2013 Justin Wilson. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package dingo
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// LogFormat is the format of the lines written to an AccessLog.
+type LogFormat int
+
+const (
+	// CommonLog is the Apache Common Log Format.
+	CommonLog LogFormat = iota
+	// CombinedLog is the Apache Combined Log Format, which includes the referer and user agent.
+	CombinedLog
+	// JSONLog writes each entry as a JSON object, including the route and latency.
+	JSONLog
+)
+
+const clfTime = "02/Jan/2006:15:04:05 -0700"
+
+// AccessEntry is a single request written to an AccessLog.
+type AccessEntry struct {
+	Time       time.Time     `json:"time"`
+	RemoteAddr string        `json:"remote_addr"`
+	User       string        `json:"user,omitempty"`
+	Method     string        `json:"method"`
+	Path       string        `json:"path"`
+	Proto      string        `json:"proto"`
+	Route      string        `json:"route,omitempty"`
+	Status     int           `json:"status"`
+	Size       int           `json:"size"`
+	Latency    time.Duration `json:"latency_ns"`
+	Referer    string        `json:"referer,omitempty"`
+	UserAgent  string        `json:"user_agent,omitempty"`
+}
+
+// AccessLog writes a line, per request, to a writer.
+type AccessLog struct {
+	mu     sync.Mutex
+	w      io.Writer
+	format LogFormat
+}
+
+// NewAccessLog returns an AccessLog writing lines, of the given format, to w.
+// Use a RotatingFile to have the log rotated by size.
+func NewAccessLog(w io.Writer, format LogFormat) *AccessLog {
+	return &AccessLog{w: w, format: format}
+}
+
+// Log writes the entry to the log.
+func (l *AccessLog) Log(e AccessEntry) error {
+	var line []byte
+	switch l.format {
+	case JSONLog:
+		b, err := json.Marshal(e)
+		if err != nil {
+			return err
+		}
+		line = append(b, '\n')
+	default:
+		size := "-"
+		if e.Size > 0 {
+			size = strconv.Itoa(e.Size)
+		}
+		line = []byte(fmt.Sprintf("%s - %s [%s] %q %d %s",
+			dash(e.RemoteAddr), dash(e.User), e.Time.Format(clfTime),
+			e.Method+" "+e.Path+" "+e.Proto, e.Status, size))
+		if l.format == CombinedLog {
+			line = append(line, fmt.Sprintf(" %q %q", e.Referer, e.UserAgent)...)
+		}
+		line = append(line, '\n')
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	_, err := l.w.Write(line)
+	return err
+}
+
+func dash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
+
+// accessEntry returns the AccessEntry for the given, served, request.
+func accessEntry(ctx Context, rt Route, start time.Time) AccessEntry {
+	e := AccessEntry{
+		Time:      start,
+		Method:    ctx.Method,
+		Path:      ctx.RequestURI,
+		Proto:     ctx.Proto,
+		Status:    ctx.Status(),
+		Size:      ctx.Size(),
+		Latency:   time.Since(start),
+		Referer:   ctx.Referer(),
+		UserAgent: ctx.UserAgent(),
+	}
+	if e.Path == "" {
+		e.Path = ctx.URL.RequestURI()
+	}
+	if e.Status == 0 {
+		// nothing was written, so the http.Server responds with a 200
+		e.Status = http.StatusOK
+	}
+	e.RemoteAddr = ctx.ClientIP()
+	if user, _, ok := ctx.BasicAuth(); ok {
+		e.User = user
+	}
+	if rt != nil {
+		e.Route = rt.Path()
+	}
+	return e
+}
@@ -0,0 +1,66 @@
+// Copyright 2013 Justin Wilson. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package dingo
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+var accessLogCol = map[LogFormat]*regexp.Regexp{
+	CommonLog:   regexp.MustCompile(`^10\.0\.0\.1 - - \[[^\]]+\] "GET /blog/2013/ HTTP/1\.1" 200 4\n$`),
+	CombinedLog: regexp.MustCompile(`^10\.0\.0\.1 - - \[[^\]]+\] "GET /blog/2013/ HTTP/1\.1" 200 4 "http://ref/" "dingo-test"\n$`),
+}
+
+func logRequest(format LogFormat) string {
+	buf := new(bytes.Buffer)
+	s := New(nil)
+	s.AccessLog = NewAccessLog(buf, format)
+	s.ReRoute("^/blog/(?P<year>\\d{4})/$", func(ctx Context) { wc(ctx, ctx.RouteData["year"]) }, "GET")
+
+	r := dummyRequest("/blog/2013/")
+	r.Proto, r.RemoteAddr, r.RequestURI = "HTTP/1.1", "10.0.0.1:1234", "/blog/2013/"
+	r.Header = make(map[string][]string)
+	r.Header.Set("Referer", "http://ref/")
+	r.Header.Set("User-Agent", "dingo-test")
+	s.ServeHTTP(httptest.NewRecorder(), r)
+
+	return buf.String()
+}
+
+func TestAccessLog(t *testing.T) {
+	for format, re := range accessLogCol {
+		if line := logRequest(format); !re.MatchString(line) {
+			t.Errorf("Unexpected access log line: %s", line)
+		}
+	}
+
+	var e AccessEntry
+	if err := json.Unmarshal([]byte(logRequest(JSONLog)), &e); err != nil {
+		t.Fatal(err)
+	}
+	if e.Route != "^/blog/(?P<year>\\d{4})/$" || e.Status != 200 || e.Size != 4 || e.RemoteAddr != "10.0.0.1" {
+		t.Errorf("Unexpected JSON access log entry: %+v", e)
+	}
+}
+
+func TestAccessLogEmpty(t *testing.T) {
+	buf := new(bytes.Buffer)
+	s := New(nil)
+	s.AccessLog = NewAccessLog(buf, CommonLog)
+	s.SRoute("/empty/", func(ctx Context) {}, "GET")
+
+	r := dummyRequest("/empty/")
+	r.Proto, r.RemoteAddr, r.RequestURI = "HTTP/1.1", "10.0.0.1:1234", "/empty/"
+	s.ServeHTTP(httptest.NewRecorder(), r)
+
+	if line := buf.String(); !strings.Contains(line, `"GET /empty/ HTTP/1.1" 200 -`) {
+		t.Errorf("Unexpected access log line: %s", line)
+	}
+}
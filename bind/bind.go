@@ -0,0 +1,205 @@
+// Copyright 2013 Justin Wilson. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package bind fills structs from the route data, query, form, files and
+// body of a request.
+package bind
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"mime/multipart"
+	"net/http"
+	"reflect"
+	"strings"
+
+	"code.minty.io/dingo"
+)
+
+var (
+	// MaxBodySize is the maximum size of a JSON or XML body.
+	MaxBodySize int64 = 1 << 20
+	// MaxMemory is the maximum size of a multipart form held in memory, the
+	// remainder being stored in temporary files.
+	MaxMemory int64 = 32 << 20
+
+	fileHeaderType  = reflect.TypeOf((*multipart.FileHeader)(nil))
+	fileHeadersType = reflect.TypeOf([]*multipart.FileHeader(nil))
+)
+
+/*-----------------------------------Errors-----------------------------------*/
+
+// FieldError is the error binding a single struct field.
+type FieldError struct {
+	Field  string
+	Source string
+	Value  string
+	Err    error
+}
+
+func (e FieldError) Error() string {
+	return fmt.Sprintf("%s: invalid %s value %q, %s", e.Field, e.Source, e.Value, e.Err)
+}
+
+// Errors is the collection of field errors from binding a struct.
+type Errors []FieldError
+
+func (e Errors) Error() string {
+	msgs := make([]string, len(e))
+	for i, err := range e {
+		msgs[i] = err.Error()
+	}
+	return strings.Join(msgs, "; ")
+}
+
+/*------------------------------------Bind------------------------------------*/
+
+// Bind fills the struct pointed to by dst from the request.
+// JSON and XML bodies are decoded into dst, per the Content-Type, after which
+// fields are filled according to their tags:
+//
+//	route:"name"  the route data
+//	form:"name"   the form, including multipart forms
+//	query:"name"  the URL query
+//	file:"name"   a multipart file, the field being a *multipart.FileHeader or []*multipart.FileHeader
+//
+// Route data takes precedence over the form, which takes precedence over the
+// query. Field values are converted with `dingo.Convert`, and slices are filled
+// from each value. Any conversion errors are returned together as Errors.
+func Bind(ctx dingo.Context, dst interface{}) error {
+	v := reflect.ValueOf(dst)
+	if v.Kind() != reflect.Ptr || v.Elem().Kind() != reflect.Struct {
+		return errors.New("bind: dst must be a pointer to a struct")
+	}
+
+	if err := body(ctx, dst); err != nil {
+		return err
+	}
+	if err := parseForm(ctx); err != nil {
+		return err
+	}
+
+	var errs Errors
+	bind(ctx, v.Elem(), &errs)
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+func body(ctx dingo.Context, dst interface{}) error {
+	if ctx.Body == nil || ctx.ContentLength == 0 {
+		return nil
+	}
+
+	var err error
+	switch ctx.ContentType() {
+	case "application/json":
+		r := http.MaxBytesReader(ctx.Response, ctx.Body, MaxBodySize)
+		err = json.NewDecoder(r).Decode(dst)
+	case "application/xml", "text/xml":
+		r := http.MaxBytesReader(ctx.Response, ctx.Body, MaxBodySize)
+		err = xml.NewDecoder(r).Decode(dst)
+	}
+	return err
+}
+
+func parseForm(ctx dingo.Context) error {
+	if ctx.ContentType() == "multipart/form-data" {
+		if ctx.MultipartForm != nil {
+			return nil
+		}
+		return ctx.ParseMultipartForm(MaxMemory)
+	}
+	return ctx.ParseForm()
+}
+
+func bind(ctx dingo.Context, v reflect.Value, errs *Errors) {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		f, fv := t.Field(i), v.Field(i)
+		if f.PkgPath != "" && !f.Anonymous {
+			// unexported
+			continue
+		}
+		if f.Anonymous && fv.Kind() == reflect.Struct {
+			bind(ctx, fv, errs)
+			continue
+		}
+
+		if name := f.Tag.Get("file"); name != "" {
+			file(ctx, name, fv)
+			continue
+		}
+
+		var source string
+		var values []string
+		if name := f.Tag.Get("query"); name != "" {
+			if vals, ok := ctx.URL.Query()[name]; ok {
+				source, values = "query", vals
+			}
+		}
+		if name := f.Tag.Get("form"); name != "" {
+			if vals, ok := ctx.PostForm[name]; ok {
+				source, values = "form", vals
+			}
+		}
+		if name := f.Tag.Get("route"); name != "" {
+			if val, ok := ctx.RouteData[name]; ok {
+				source, values = "route", []string{val}
+			}
+		}
+		if source == "" {
+			continue
+		}
+
+		if val, err := set(fv, values); err != nil {
+			*errs = append(*errs, FieldError{f.Name, source, val, err})
+		}
+	}
+}
+
+// set converts the values into the field, returning the value that failed on error.
+func set(fv reflect.Value, values []string) (string, error) {
+	t := fv.Type()
+	if t.Kind() == reflect.Slice && t.Elem().Kind() != reflect.Uint8 {
+		s := reflect.MakeSlice(t, 0, len(values))
+		for _, val := range values {
+			e, err := dingo.Convert(val, t.Elem())
+			if err != nil {
+				return val, err
+			}
+			s = reflect.Append(s, e)
+		}
+		fv.Set(s)
+		return "", nil
+	}
+
+	val := values[0]
+	e, err := dingo.Convert(val, t)
+	if err != nil {
+		return val, err
+	}
+	fv.Set(e)
+	return "", nil
+}
+
+func file(ctx dingo.Context, name string, fv reflect.Value) {
+	if ctx.MultipartForm == nil {
+		return
+	}
+	files := ctx.MultipartForm.File[name]
+	if len(files) == 0 {
+		return
+	}
+
+	switch fv.Type() {
+	case fileHeaderType:
+		fv.Set(reflect.ValueOf(files[0]))
+	case fileHeadersType:
+		fv.Set(reflect.ValueOf(files))
+	}
+}
@@ -0,0 +1,97 @@
+// Copyright 2013 Justin Wilson. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package bind
+
+import (
+	"bytes"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"code.minty.io/dingo"
+)
+
+type Paging struct {
+	Page int `query:"page"`
+}
+
+type post struct {
+	Paging
+	ID     int                   `route:"id"`
+	Title  string                `json:"title" form:"title"`
+	Tags   []string              `query:"tag"`
+	Draft  bool                  `form:"draft"`
+	Date   time.Time             `query:"date"`
+	Upload *multipart.FileHeader `file:"upload"`
+}
+
+func context(r *http.Request, data map[string]string) dingo.Context {
+	ctx := dingo.NewContext(httptest.NewRecorder(), r)
+	ctx.RouteData = data
+	return ctx
+}
+
+func TestBindForm(t *testing.T) {
+	r := httptest.NewRequest("POST", "/posts/7/?page=2&tag=a&tag=b&date=2013-05-09", strings.NewReader("title=Hello&draft=true"))
+	r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	var p post
+	if err := Bind(context(r, map[string]string{"id": "7"}), &p); err != nil {
+		t.Fatal(err)
+	}
+	if p.ID != 7 || p.Page != 2 || p.Title != "Hello" || !p.Draft || len(p.Tags) != 2 || p.Date.Day() != 9 {
+		t.Errorf("Unexpected bound struct: %+v", p)
+	}
+}
+
+func TestBindJSON(t *testing.T) {
+	r := httptest.NewRequest("PUT", "/posts/7/", strings.NewReader(`{"title": "Hello"}`))
+	r.Header.Set("Content-Type", "application/json")
+
+	var p post
+	if err := Bind(context(r, map[string]string{"id": "7"}), &p); err != nil {
+		t.Fatal(err)
+	}
+	if p.ID != 7 || p.Title != "Hello" {
+		t.Errorf("Unexpected bound struct: %+v", p)
+	}
+}
+
+func TestBindMultipart(t *testing.T) {
+	buf := new(bytes.Buffer)
+	w := multipart.NewWriter(buf)
+	w.WriteField("title", "Hello")
+	f, _ := w.CreateFormFile("upload", "a.txt")
+	f.Write([]byte("data"))
+	w.Close()
+
+	r := httptest.NewRequest("POST", "/posts/", buf)
+	r.Header.Set("Content-Type", w.FormDataContentType())
+
+	var p post
+	if err := Bind(context(r, nil), &p); err != nil {
+		t.Fatal(err)
+	}
+	if p.Title != "Hello" || p.Upload == nil || p.Upload.Filename != "a.txt" {
+		t.Errorf("Unexpected bound struct: %+v", p)
+	}
+}
+
+func TestBindErrors(t *testing.T) {
+	r := httptest.NewRequest("GET", "/posts/abc/?page=x&date=never", nil)
+
+	var p post
+	err := Bind(context(r, map[string]string{"id": "abc"}), &p)
+	errs, ok := err.(Errors)
+	if !ok || len(errs) != 3 {
+		t.Fatalf("Expected 3 field errors, got: %v", err)
+	}
+	if errs[0].Field != "Page" || errs[0].Source != "query" || errs[0].Value != "x" {
+		t.Errorf("Unexpected field error: %+v", errs[0])
+	}
+}
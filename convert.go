@@ -0,0 +1,96 @@
+// Copyright 2013 Justin Wilson. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package dingo
+
+import (
+	"encoding"
+	"fmt"
+	"reflect"
+	"strconv"
+	"time"
+)
+
+var (
+	contextType         = reflect.TypeOf(Context{})
+	timeType            = reflect.TypeOf(time.Time{})
+	textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+
+	// TimeFormats are the layouts, in order, used to parse `time.Time` params.
+	TimeFormats = []string{time.RFC3339, "2006-01-02"}
+)
+
+// converter converts a RegEx group to a handler param.
+type converter func(s string) (reflect.Value, error)
+
+// newConverter returns a converter for the given type, or nil if the type isn't supported.
+func newConverter(t reflect.Type) converter {
+	switch {
+	case t == timeType:
+		return convertTime
+	case reflect.PtrTo(t).Implements(textUnmarshalerType):
+		return func(s string) (reflect.Value, error) {
+			v := reflect.New(t)
+			err := v.Interface().(encoding.TextUnmarshaler).UnmarshalText([]byte(s))
+			return v.Elem(), err
+		}
+	case t.Kind() == reflect.Ptr && t.Implements(textUnmarshalerType):
+		return func(s string) (reflect.Value, error) {
+			v := reflect.New(t.Elem())
+			err := v.Interface().(encoding.TextUnmarshaler).UnmarshalText([]byte(s))
+			return v, err
+		}
+	}
+
+	switch t.Kind() {
+	case reflect.String:
+		return func(s string) (reflect.Value, error) {
+			return reflect.ValueOf(s).Convert(t), nil
+		}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return func(s string) (reflect.Value, error) {
+			n, err := strconv.ParseInt(s, 10, t.Bits())
+			return reflect.ValueOf(n).Convert(t), err
+		}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return func(s string) (reflect.Value, error) {
+			n, err := strconv.ParseUint(s, 10, t.Bits())
+			return reflect.ValueOf(n).Convert(t), err
+		}
+	case reflect.Float32, reflect.Float64:
+		return func(s string) (reflect.Value, error) {
+			n, err := strconv.ParseFloat(s, t.Bits())
+			return reflect.ValueOf(n).Convert(t), err
+		}
+	case reflect.Bool:
+		return func(s string) (reflect.Value, error) {
+			b, err := strconv.ParseBool(s)
+			return reflect.ValueOf(b).Convert(t), err
+		}
+	}
+	return nil
+}
+
+func convertTime(s string) (reflect.Value, error) {
+	var (
+		t   time.Time
+		err error
+	)
+	for _, f := range TimeFormats {
+		if t, err = time.Parse(f, s); err == nil {
+			break
+		}
+	}
+	return reflect.ValueOf(t), err
+}
+
+// Convert converts the string to a value of the given type, supporting the
+// same types as RRoute handler params.
+func Convert(s string, t reflect.Type) (reflect.Value, error) {
+	conv := newConverter(t)
+	if conv == nil {
+		return reflect.Value{}, fmt.Errorf("unsupported type: %v", t)
+	}
+	return conv(s)
+}
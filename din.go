@@ -7,12 +7,16 @@
 package dingo
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"log"
 	"net"
 	"net/http"
 	"path"
-	"runtime"
+	"strings"
+	"sync"
+	"time"
 )
 
 const (
@@ -29,6 +33,7 @@ var (
 		"DELETE",
 		"TRACE",
 		"CONNECT",
+		"PATCH",
 	}
 )
 
@@ -53,34 +58,50 @@ type Context struct {
 	*http.Request
 	Response  http.ResponseWriter
 	RouteData map[string]string
+	// Err is the error which caused the current http error, if any.
+	Err     error
+	onError Error
+	proxies proxies
 }
 
 // NewContext creates, and returns, a new Context
 func NewContext(response http.ResponseWriter, request *http.Request) Context {
 	c := new(Context)
-	c.Request, c.Response = request, response
+	c.Request, c.Response = withValues(request), response
 	return *c
 }
 
-// Redirect issues a redirect to the response.
+// Redirect issues a redirect to the response, to the absolute URL of path.
 func (c *Context) Redirect(path string) {
-	http.Redirect(c.Response, c.Request, path, http.StatusFound)
+	http.Redirect(c.Response, c.Request, c.AbsoluteURL(path), http.StatusFound)
 }
 
-// RedirectPerm issues a permanent redirect to the response.
+// RedirectPerm issues a permanent redirect to the response, to the absolute URL of path.
 func (c *Context) RedirectPerm(path string) {
 	r := c.Response
-	r.Header().Set("Location", path)
+	r.Header().Set("Location", c.AbsoluteURL(path))
 	r.WriteHeader(http.StatusMovedPermanently)
 }
 
+// Fail issues the given http error (status), making err available to the error
+// handlers as the Context's Err.
+func (c *Context) Fail(status int, err error) {
+	c.Err = err
+	c.HttpError(status)
+}
+
 // HttpError issues the given http error (status) and writes any provided msgs.
-// The ErrorHandler is invoked when it has been set and there are no provided msgs.
+// The error handler of the route's Group, or those registered with the Server,
+// or the ErrorHandler, is invoked when it has been set and there are no provided msgs.
 func (c *Context) HttpError(status int, msgs ...string) {
+	handler := ErrorHandler
+	if c.onError != nil {
+		handler = c.onError
+	}
 	//if ErrorHandler != nil {
-	if ErrorHandler != nil && msgs == nil {
+	if handler != nil && msgs == nil {
 		// should we defer a panic here? Or just assume you know what you're doing?
-		if ErrorHandler(*c, status) {
+		if handler(*c, status) {
 			return
 		}
 	}
@@ -91,7 +112,9 @@ func (c *Context) HttpError(status int, msgs ...string) {
 		// TODO Log an error
 	}
 	r := c.Response
-	r.WriteHeader(status)
+	if !c.Written() {
+		r.WriteHeader(status)
+	}
 	//if msgs == nil {
 	if len(msgs) == 0 {
 		m := []byte(http.StatusText(status))
@@ -103,6 +126,31 @@ func (c *Context) HttpError(status int, msgs ...string) {
 	}
 }
 
+// Written returns whether the response header has been written.
+func (c *Context) Written() bool {
+	if w, ok := c.Response.(ResponseWriter); ok {
+		return w.Written()
+	}
+	return false
+}
+
+// Status returns the status written to the response, or zero if the header
+// hasn't been written.
+func (c *Context) Status() int {
+	if w, ok := c.Response.(ResponseWriter); ok {
+		return w.Status()
+	}
+	return 0
+}
+
+// Size returns the number of bytes of the response body written.
+func (c *Context) Size() int {
+	if w, ok := c.Response.(ResponseWriter); ok {
+		return w.Size()
+	}
+	return 0
+}
+
 /*----------------------------------Route-------------------------------------*/
 
 // Route is the handling object when a request matches.
@@ -160,21 +208,45 @@ func iroute(route newRoute) NewIRoute {
 // NewIRoute returns a new route for the given path and handler.
 type NewIRoute func(path string, h interface{}) Route
 type Router struct {
-	svr   *Server
-	path  string
-	route NewIRoute
+	svr        *Server
+	path       string
+	route      NewIRoute
+	middleware []Middleware
+	name       string
 }
 
 // NewRouter returns a router.
 func NewRouter(s *Server, path string, route NewIRoute) Router {
-	return Router{s, path, route}
+	return Router{svr: s, path: path, route: route}
 }
 func (r Router) add(h interface{}, m string) Router {
-	route := r.route(r.path, h)
+	route := WrapRoute(r.route(r.path, h), r.middleware...)
+	if r.name != "" {
+		r.svr.Name(r.name, route)
+	}
 	r.svr.Route(route, m)
 	return r
 }
 
+// Name returns a Router that names the routes it adds, see `Server.URL`.
+func (r Router) Name(name string) Router {
+	r.name = name
+	return r
+}
+
+// Use returns a Router that wraps the routes it adds with the given middleware.
+// Router middleware is invoked, in order, after any Server middleware.
+func (r Router) Use(mw ...Middleware) Router {
+	m := make([]Middleware, 0, len(r.middleware)+len(mw))
+	r.middleware = append(append(m, r.middleware...), mw...)
+	return r
+}
+
+// Timeout returns a Router whose routes have the given deadline, see `Timeout`.
+func (r Router) Timeout(d time.Duration) Router {
+	return r.Use(Timeout(d))
+}
+
 // Options adds a route for "OPTIONS" method.
 func (r Router) Options(h interface{}) Router {
 	return r.add(h, "OPTIONS")
@@ -185,6 +257,12 @@ func (r Router) Get(h interface{}) Router {
 	return r.add(h, "GET")
 }
 
+// Head adds a route for "HEAD" method.
+// HEAD requests are answered by the "GET" route, when there isn't a "HEAD" route.
+func (r Router) Head(h interface{}) Router {
+	return r.add(h, "HEAD")
+}
+
 // Options adds a route for "POST" method.
 func (r Router) Post(h interface{}) Router {
 	return r.add(h, "POST")
@@ -210,12 +288,41 @@ func (r Router) Connect(h interface{}) Router {
 	return r.add(h, "CONNECT")
 }
 
+// Patch adds a route for "PATCH" method.
+func (r Router) Patch(h interface{}) Router {
+	return r.add(h, "PATCH")
+}
+
 /*-----------------------------------Server-----------------------------------*/
 
 // Server implements http.Handler and routes calls to handlers view a Routes collection.
 type Server struct {
-	listener net.Listener
-	routes   map[string]Routes
+	listeners  []net.Listener
+	routes     map[string]Routes
+	newRoutes  func() Routes
+	middleware []Middleware
+	names      map[string]*reverse
+	srv        *http.Server
+	shutdown   *sync.Once
+	drained    chan struct{}
+
+	// Timeouts of the underlying `http.Server`, zero being no timeout.
+	ReadTimeout, WriteTimeout, IdleTimeout time.Duration
+
+	// Reporter receives panics recovered while serving, defaulting to LogReporter.
+	Reporter Reporter
+	// Debug renders recovered panics, with their stack, as the 500 response.
+	Debug bool
+	// AccessLog, when set, has a line written to it for every request.
+	AccessLog *AccessLog
+	errors    *errorHandlers
+	fallback  Handler
+	hsts      string
+	proxies   proxies
+
+	// ProxyHeader is the forwarding header honored from the proxies trusted
+	// by `TrustProxies`, defaulting to the `X-Forwarded-*` headers.
+	ProxyHeader ProxyHeader
 }
 
 // IsCanonical returns wether the given path is canonical.
@@ -237,17 +344,37 @@ func IsCanonical(p string) (string, bool) {
 }
 
 func (s *Server) initRoutes() {
-	s.routes = make(map[string]Routes)
+	if s.routes == nil {
+		s.routes = make(map[string]Routes)
+	}
+	if s.newRoutes == nil {
+		s.newRoutes = NewListRoutes
+	}
 	for _, method := range httpMethods {
-		s.routes[method] = new(routes)
+		s.routes[method] = s.newRoutes()
 	}
 }
 
-// New returns a new Server.
-func New(l net.Listener) Server {
+// SetRoutes sets the func used to create the Routes collection for each method,
+// eg. `NewTreeRoutes`. Any routes already added to the server are discarded, so
+// this should be called prior to adding routes.
+func (s *Server) SetRoutes(fn func() Routes) {
+	s.newRoutes = fn
+	s.initRoutes()
+}
+
+// New returns a new Server, serving the given listeners.
+func New(listeners ...net.Listener) Server {
 	s := new(Server)
 	s.initRoutes()
-	s.listener = l
+	for _, l := range listeners {
+		s.AddListener(l)
+	}
+	s.names = make(map[string]*reverse)
+	s.srv = new(http.Server)
+	s.shutdown = new(sync.Once)
+	s.drained = make(chan struct{})
+	s.errors = new(errorHandlers)
 
 	return *s
 }
@@ -263,6 +390,37 @@ func (s *Server) Route(rt Route, methods ...string) {
 	}
 }
 
+// NamedRoute adds a route for the given methods, naming it for use with `URL`.
+func (s *Server) NamedRoute(name string, rt Route, methods ...string) {
+	s.Name(name, rt)
+	s.Route(rt, methods...)
+}
+
+// Name names the given route for use with `URL`.
+// Panics when the name is already used by a route with a different path, or
+// when a path can't be built from the route's pattern.
+func (s *Server) Name(name string, rt Route) {
+	r, err := newReverse(rt)
+	if err != nil {
+		panic(err)
+	}
+	if n, ok := s.names[name]; ok && n.expr.String() != r.expr.String() {
+		panic(fmt.Sprintf("Route name already used: %s", name))
+	}
+	s.names[name] = r
+}
+
+// URL returns the path of the named route, built from the given params which
+// replace the route's RegEx groups, or named params, in order.
+// Each param must match the group it replaces.
+func (s *Server) URL(name string, params ...interface{}) (string, error) {
+	r, ok := s.names[name]
+	if !ok {
+		return "", fmt.Errorf("No route named: %s", name)
+	}
+	return r.build(params...)
+}
+
 // SRoute adds a new static route.
 func (s *Server) SRoute(path string, handler Handler, methods ...string) {
 	rt := NewSRoute(path, handler)
@@ -281,68 +439,220 @@ func (s *Server) RRoute(path string, handler interface{}, methods ...string) {
 	s.Route(rt, methods...)
 }
 
+// PRoute adds a new named param route, eg. `/blog/:year/{slug}/`.
+func (s *Server) PRoute(path string, handler Handler, methods ...string) {
+	rt := NewPRoute(path, handler)
+	s.Route(rt, methods...)
+}
+
 // SRouter returns a new Router used to add static routes to the server.
 func (s *Server) SRouter(p string) Router {
-	return Router{s, p, iroute(NewSRoute)}
+	return NewRouter(s, p, iroute(NewSRoute))
 }
 
 // SRouter returns a new Router used to add RegEx routes to the server.
 func (s *Server) ReRouter(p string) Router {
-	return Router{s, p, iroute(NewReRoute)}
+	return NewRouter(s, p, iroute(NewReRoute))
 }
 
 // SRouter returns a new Router used to add RegEx routes to the server where the params are passed to the handler.
 func (s *Server) RRouter(p string) Router {
-	return Router{s, p, NewRRoute}
+	return NewRouter(s, p, NewRRoute)
 }
 
-// Serve begins listening for requests
-func (s *Server) Serve() {
-	http.Serve(s.listener, s)
+// PRouter returns a new Router used to add named param routes to the server.
+func (s *Server) PRouter(p string) Router {
+	return NewRouter(s, p, iroute(NewPRoute))
+}
+
+// Use adds middleware invoked, in order, around every route of the server.
+func (s *Server) Use(mw ...Middleware) {
+	s.middleware = append(s.middleware, mw...)
+}
+
+// AddListener adds a listener to be served, it must be added prior to `Serve`.
+func (s *Server) AddListener(l net.Listener) {
+	if l != nil {
+		s.listeners = append(s.listeners, l)
+	}
+}
+
+// Listeners returns the listeners of the server.
+func (s *Server) Listeners() []net.Listener {
+	return s.listeners
+}
+
+// ListenerError is the error of a single listener.
+type ListenerError struct {
+	Listener net.Listener
+	Err      error
+}
+
+func (e ListenerError) Error() string {
+	return fmt.Sprintf("%s: %s", e.Listener.Addr(), e.Err)
+}
+
+// ListenerErrors is the collection of errors returned by Serve.
+type ListenerErrors []ListenerError
+
+func (e ListenerErrors) Error() string {
+	msgs := make([]string, len(e))
+	for i, err := range e {
+		msgs[i] = err.Error()
+	}
+	return strings.Join(msgs, "; ")
+}
+
+// Serve begins listening for requests on all listeners concurrently, returning
+// once every listener has failed or the server has been shutdown and in-flight
+// requests have drained. Listener failures are logged as they occur, and
+// returned together as ListenerErrors.
+func (s *Server) Serve() error {
+	if len(s.listeners) == 0 {
+		return errors.New("dingo: no listeners to serve")
+	}
+	s.srv.Handler = s
+	s.srv.ReadTimeout = s.ReadTimeout
+	s.srv.WriteTimeout = s.WriteTimeout
+	s.srv.IdleTimeout = s.IdleTimeout
+
+	results := make(chan ListenerError, len(s.listeners))
+	for _, l := range s.listeners {
+		go func(l net.Listener) {
+			err := s.srv.Serve(l)
+			if err != http.ErrServerClosed {
+				log.Printf("dingo: listener %s failed, %s\n", l.Addr(), err)
+			}
+			results <- ListenerError{l, err}
+		}(l)
+	}
+
+	var errs ListenerErrors
+	closed := false
+	for range s.listeners {
+		r := <-results
+		if r.Err == http.ErrServerClosed {
+			closed = true
+		} else {
+			errs = append(errs, r)
+		}
+	}
+	if closed {
+		<-s.drained
+	}
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+// Shutdown stops the server from accepting new connections and waits for
+// in-flight requests to finish. When the context expires first, remaining
+// connections are closed and the context's error is returned.
+func (s *Server) Shutdown(ctx context.Context) (err error) {
+	s.shutdown.Do(func() {
+		if err = s.srv.Shutdown(ctx); err != nil {
+			s.srv.Close()
+		}
+		close(s.drained)
+	})
+	return
 }
 
 /* http.Handler */
 func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
-	ctx := NewContext(w, r)
-	defer _500Handler(ctx)
-
-	if routes, ok := s.routes[r.Method]; ok {
-		path, canonical := IsCanonical(r.URL.Path)
-		if rt, ok := routes.Route(path); ok {
-			if rt.IsCanonical() && !canonical {
-				r.URL.Path = path
-				ctx.RedirectPerm(r.URL.String())
-			} else {
-				rt.Execute(ctx)
+	start := time.Now()
+	res := NewResponseWriter(w)
+	ctx := NewContext(res, r)
+	ctx.onError = s.errors.handle
+	ctx.proxies = proxies{s.proxies.trusted, s.ProxyHeader}
+
+	var rt Route
+	defer func() {
+		if v := recover(); v != nil {
+			s.recovered(ctx, res, rt, v)
+		}
+		if s.AccessLog != nil {
+			ctx.Response = res
+			if err := s.AccessLog.Log(accessEntry(ctx, rt, start)); err != nil {
+				log.Println("dingo: access log error, ", err)
 			}
+		}
+	}()
+
+	if s.hsts != "" && r.TLS != nil {
+		res.Header().Set("Strict-Transport-Security", s.hsts)
+	}
+
+	path, canonical := IsCanonical(r.URL.Path)
+	var head *headResponse
+	rt, ok := s.route(r.Method, path)
+	if !ok && r.Method == "HEAD" {
+		// answer with the GET route, discarding the body
+		if rt, ok = s.route("GET", path); ok {
+			head = &headResponse{ResponseWriter: res}
+			ctx.Response = head
+		}
+	}
+	if ok {
+		if ctx.Canceled() {
+			// the client has gone away, there's nobody to respond to
 			return
 		}
+		if rt.IsCanonical() && !canonical {
+			r.URL.Path = path
+			ctx.RedirectPerm(r.URL.String())
+		} else {
+			Chain(rt.Execute, s.middleware...)(ctx)
+		}
+		if head != nil {
+			head.flush()
+		}
+		return
+	}
+
+	if s.fallback != nil {
+		s.fallback(ctx)
+		return
+	}
+
+	// the path may exist for other methods
+	if methods := s.Allowed(path); len(methods) > 0 {
+		ctx.Response.Header().Set("Allow", strings.Join(methods, ", "))
+		if r.Method == "OPTIONS" {
+			ctx.Response.WriteHeader(http.StatusOK)
+		} else {
+			ctx.HttpError(http.StatusMethodNotAllowed)
+		}
+		return
 	}
 
 	ctx.HttpError(404)
 }
 
-/*--------------*/
-
-func _500Handler(ctx Context) {
-	//if err, ok := recover().(error); ok {
-	if err := recover(); err != nil {
-		// TODO write the 500 message, or stack, depending on some settings
-		// if !emitError
-		ctx.HttpError(500)
-
-		log.Println("_______________________________________ERR______________________________________")
-		log.Println(err)
-		for i := 1; ; i++ {
-			if _, f, l, ok := runtime.Caller(i); !ok {
-				break
-			} else {
-				//p := strings.Split(f, "/")
-				//fmt.Printf("%s : %d\n", p[len(p)-1], l)
-				//fmt.Printf("%s\n%d\n_________________________\n", f, l)
-				log.Printf("Line: %d\nfile: %s\n-\n", l, f)
+func (s *Server) route(method, path string) (Route, bool) {
+	if routes, ok := s.routes[method]; ok {
+		return routes.Route(path)
+	}
+	return nil, false
+}
+
+// Allowed returns the methods which have a route matching the given path.
+// "OPTIONS", and "HEAD" when there is a "GET" route, are included for a matching
+// path as they are answered automatically.
+func (s *Server) Allowed(path string) []string {
+	var methods []string
+	for _, m := range httpMethods {
+		if _, ok := s.route(m, path); ok {
+			methods = append(methods, m)
+		} else if m == "HEAD" {
+			if _, ok := s.route("GET", path); ok {
+				methods = append(methods, m)
 			}
 		}
-		log.Println("________________________________________________________________________________")
 	}
+	if len(methods) > 0 && methods[0] != "OPTIONS" {
+		methods = append([]string{"OPTIONS"}, methods...)
+	}
+	return methods
 }
@@ -5,7 +5,14 @@
 package dingo
 
 import (
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
 	"testing"
+	"time"
 )
 
 var canonicalPaths = []string{
@@ -38,3 +45,292 @@ func TestIsNotCanonical(t *testing.T) {
 		}
 	}
 }
+
+func TestMethodNotAllowed(t *testing.T) {
+	s := New(nil)
+	s.SRoute("/resource/", dummyHandler, "GET", "POST")
+
+	w := serve(&s, "DELETE", "/resource/")
+	if w.Code != http.StatusMethodNotAllowed {
+		t.Errorf("Unexpected status: %d != 405", w.Code)
+	} else if a := w.Header().Get("Allow"); a != "OPTIONS, GET, HEAD, POST" {
+		t.Errorf("Unexpected Allow header: %s", a)
+	}
+
+	if w = serve(&s, "DELETE", "/missing/"); w.Code != http.StatusNotFound {
+		t.Errorf("Unexpected status: %d != 404", w.Code)
+	}
+}
+
+func TestOptions(t *testing.T) {
+	s := New(nil)
+	s.SRoute("/resource/", dummyHandler, "GET")
+	s.SRoute("/explicit/", func(ctx Context) { ctx.Response.WriteHeader(http.StatusNoContent) }, "OPTIONS", "PUT")
+
+	if w := serve(&s, "OPTIONS", "/resource/"); w.Code != http.StatusOK {
+		t.Errorf("Unexpected status: %d != 200", w.Code)
+	} else if a := w.Header().Get("Allow"); a != "OPTIONS, GET, HEAD" {
+		t.Errorf("Unexpected Allow header: %s", a)
+	}
+
+	if w := serve(&s, "OPTIONS", "/explicit/"); w.Code != http.StatusNoContent {
+		t.Errorf("Explicit OPTIONS route wasn't invoked: %d", w.Code)
+	}
+}
+
+func TestHead(t *testing.T) {
+	s := New(nil)
+	s.SRoute("/page/", dummyHandler_0, "GET")
+	s.SRoute("/sized/", func(ctx Context) {
+		ctx.Response.Header().Set("Content-Length", "1024")
+		wc(ctx, "partial")
+	}, "GET")
+	s.SRouter("/explicit/").Head(func(ctx Context) { ctx.Response.WriteHeader(http.StatusNoContent) })
+
+	w := serve(&s, "HEAD", "/page/")
+	if w.Code != http.StatusOK || w.Body.Len() != 0 {
+		t.Errorf("Unexpected HEAD response: %d, %q", w.Code, w.Body.String())
+	} else if l := w.Header().Get("Content-Length"); l != "6" {
+		t.Errorf("Unexpected Content-Length: %s != 6", l)
+	}
+
+	if w = serve(&s, "HEAD", "/sized/"); w.Header().Get("Content-Length") != "1024" {
+		t.Errorf("Content-Length wasn't preserved: %s", w.Header().Get("Content-Length"))
+	}
+	if w = serve(&s, "HEAD", "/explicit/"); w.Code != http.StatusNoContent {
+		t.Errorf("Explicit HEAD route wasn't invoked: %d", w.Code)
+	}
+}
+
+func TestRecover(t *testing.T) {
+	var reported Panic
+	s := New(nil)
+	s.Reporter = ReporterFunc(func(p Panic) { reported = p })
+	s.SRoute("/panic/", func(ctx Context) { panic("boom") }, "GET")
+	s.SRoute("/started/", func(ctx Context) {
+		ctx.Response.WriteHeader(http.StatusAccepted)
+		panic("boom")
+	}, "GET")
+
+	if w := serve(&s, "GET", "/panic/"); w.Code != http.StatusInternalServerError {
+		t.Errorf("Unexpected status: %d != 500", w.Code)
+	} else if reported.Value != "boom" || reported.Route != "/panic/" || len(reported.Stack) == 0 {
+		t.Errorf("Unexpected panic report: %v", reported)
+	}
+
+	if w := serve(&s, "GET", "/started/"); w.Code != http.StatusAccepted || w.Body.Len() != 0 {
+		t.Errorf("Response was written after being started: %d, %s", w.Code, w.Body.String())
+	}
+
+	s.Debug = true
+	if w := serve(&s, "GET", "/panic/"); !strings.Contains(w.Body.String(), "panic: boom") {
+		t.Errorf("Debug response is missing the panic: %s", w.Body.String())
+	}
+}
+
+func TestResponseWriter(t *testing.T) {
+	s := New(nil)
+	s.SRoute("/written/", func(ctx Context) {
+		if ctx.Written() {
+			t.Error("Response written before the handler")
+		}
+		wc(ctx, "partial")
+		ctx.HttpError(500)
+		if ctx.Status() != http.StatusOK || ctx.Size() != 7+len(http.StatusText(500)) {
+			t.Errorf("Unexpected response status/size: %d/%d", ctx.Status(), ctx.Size())
+		}
+		if _, ok := ctx.Response.(http.Flusher); !ok {
+			t.Error("Response isn't a Flusher")
+		}
+	}, "GET")
+
+	if w := serve(&s, "GET", "/written/"); w.Code != http.StatusOK {
+		t.Errorf("Status was rewritten after the header was written: %d", w.Code)
+	}
+}
+
+func TestHandleError(t *testing.T) {
+	handler := func(name string) Error {
+		return func(ctx Context, status int) bool {
+			ctx.Response.WriteHeader(status)
+			wc(ctx, fmt.Sprint(name, ":", ctx.Err))
+			return true
+		}
+	}
+
+	s := New(nil)
+	s.HandleError(404, handler("404"))
+	s.HandleErrorRange(400, 499, handler("4xx"))
+	s.SRoute("/forbidden/", func(ctx Context) { ctx.Fail(403, errors.New("denied")) }, "GET")
+	s.SRoute("/panic/", func(ctx Context) { panic("boom") }, "GET")
+	s.Reporter = ReporterFunc(func(p Panic) {})
+
+	responses := map[string]string{
+		"/missing/":   "404:<nil>",
+		"/forbidden/": "4xx:denied",
+		"/panic/":     http.StatusText(500),
+	}
+	for path, body := range responses {
+		if w := serve(&s, "GET", path); w.Body.String() != body {
+			t.Errorf("Unexpected error response for (%s): %s != %s", path, w.Body.String(), body)
+		}
+	}
+}
+
+func TestRedirectTLS(t *testing.T) {
+	redirects := map[string]string{
+		"":            "https://www.juzt.in/a/b/?c=d",
+		"example.com": "https://example.com:8443/a/b/?c=d",
+	}
+	for host, location := range redirects {
+		port := 443
+		if host != "" {
+			port = 8443
+		}
+		s := NewRedirectTLS(nil, host, port)
+		if w := serve(&s, "GET", "/a/b/?c=d"); w.Code != http.StatusMovedPermanently || w.Header().Get("Location") != location {
+			t.Errorf("Unexpected redirect: %d, %s != %s", w.Code, w.Header().Get("Location"), location)
+		}
+		if w := serve(&s, "POST", "/a/b/?c=d"); w.Code != http.StatusPermanentRedirect {
+			t.Errorf("Unexpected redirect status: %d != 308", w.Code)
+		}
+	}
+
+	// request hosts, by port, and their locations
+	hosts := []struct {
+		host     string
+		port     int
+		location string
+	}{
+		{"[::1]:80", 443, "https://[::1]/x"},
+		{"[::1]", 8443, "https://[::1]:8443/x"},
+		{"example.com:80", 8443, "https://example.com:8443/x"},
+	}
+	for _, h := range hosts {
+		s := NewRedirectTLS(nil, "", h.port)
+		w := httptest.NewRecorder()
+		r := dummyRequest("/x")
+		r.Host = h.host
+		s.ServeHTTP(w, r)
+		if l := w.Header().Get("Location"); l != h.location {
+			t.Errorf("Unexpected redirect for (%s): %s != %s", h.host, l, h.location)
+		}
+	}
+
+	s := NewRedirectTLS(nil, "", 443)
+	w := httptest.NewRecorder()
+	r := dummyRequest("/a/")
+	r.Host = ""
+	s.ServeHTTP(w, r)
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("Unexpected status for a request without a host: %d != 400", w.Code)
+	}
+}
+
+func TestStrictTransportSecurity(t *testing.T) {
+	s := New(nil)
+	s.StrictTransportSecurity(365*24*time.Hour, true, false)
+	s.SRoute("/", dummyHandler, "GET")
+
+	if w := serve(&s, "GET", "/"); w.Header().Get("Strict-Transport-Security") != "" {
+		t.Error("HSTS header emitted on a non TLS response")
+	}
+
+	hsts := func() string {
+		w := httptest.NewRecorder()
+		r := dummyRequest("/")
+		r.TLS = new(tls.ConnectionState)
+		s.ServeHTTP(w, r)
+		return w.Header().Get("Strict-Transport-Security")
+	}
+	if h := hsts(); h != "max-age=31536000; includeSubDomains" {
+		t.Errorf("Unexpected HSTS header: %s", h)
+	}
+
+	s.StrictTransportSecurity(0, false, false)
+	if h := hsts(); h != "max-age=0" {
+		t.Errorf("Unexpected HSTS header: %s != max-age=0", h)
+	}
+	s.StrictTransportSecurity(-1, false, false)
+	if h := hsts(); h != "" {
+		t.Errorf("HSTS header wasn't removed: %s", h)
+	}
+}
+
+func TestForwarded(t *testing.T) {
+	s := New(nil)
+	if err := s.TrustProxies("10.0.0.0/8", "192.0.2.1"); err != nil {
+		t.Fatal(err)
+	}
+	s.SRoute("/client/", func(ctx Context) {
+		wc(ctx, ctx.ClientIP()+" "+ctx.ClientScheme()+" "+ctx.ClientHost())
+	}, "GET")
+	s.SRoute("/moved/", func(ctx Context) { ctx.Redirect("moved/to/") }, "GET")
+
+	tests := []struct {
+		peer    string
+		header  ProxyHeader
+		headers map[string]string
+		body    string
+	}{
+		{"203.0.113.9:1234", XForwardedHeader, map[string]string{"X-Forwarded-For": "198.51.100.1"}, "203.0.113.9 http www.juzt.in"},
+		{"10.0.0.1:1234", XForwardedHeader, nil, "10.0.0.1 http www.juzt.in"},
+		{"10.0.0.1:1234", XForwardedHeader, map[string]string{"X-Real-IP": "198.51.100.1", "X-Forwarded-Proto": "https"}, "198.51.100.1 https www.juzt.in"},
+		{"10.0.0.1:1234", XForwardedHeader, map[string]string{
+			"X-Forwarded-For":   "1.1.1.1, 198.51.100.1, 192.0.2.1",
+			"X-Forwarded-Proto": "https",
+			"X-Forwarded-Host":  "proxied.com",
+		}, "198.51.100.1 https proxied.com"},
+		// a Forwarded header sent by the client isn't honored
+		{"10.0.0.1:1234", XForwardedHeader, map[string]string{
+			"Forwarded":       "for=1.2.3.4;proto=https",
+			"X-Forwarded-For": "5.6.7.8",
+		}, "5.6.7.8 http www.juzt.in"},
+		{"10.0.0.1:1234", ForwardedHeader, map[string]string{
+			"Forwarded": `for=1.1.1.1, for="[2001:db8::7]:4711";proto=https;host="proxied.com", for=10.1.1.1;proto=http`,
+		}, "2001:db8::7 https proxied.com"},
+		{"10.0.0.1:1234", ForwardedHeader, map[string]string{"Forwarded": "for=10.2.2.2;proto=https, for=10.1.1.1"}, "10.2.2.2 https www.juzt.in"},
+		// X-Forwarded-* headers sent by the client aren't honored
+		{"10.0.0.1:1234", ForwardedHeader, map[string]string{
+			"Forwarded":         "for=5.6.7.8",
+			"X-Forwarded-For":   "1.2.3.4",
+			"X-Forwarded-Proto": "https",
+		}, "5.6.7.8 http www.juzt.in"},
+		{"10.0.0.1:1234", ForwardedHeader, map[string]string{"X-Real-IP": "1.2.3.4"}, "10.0.0.1 http www.juzt.in"},
+	}
+	for _, test := range tests {
+		w := httptest.NewRecorder()
+		r := dummyRequest("/client/")
+		r.RemoteAddr, r.Header = test.peer, http.Header{}
+		s.ProxyHeader = test.header
+		for k, v := range test.headers {
+			r.Header.Set(k, v)
+		}
+		s.ServeHTTP(w, r)
+		if w.Body.String() != test.body {
+			t.Errorf("Unexpected client for %s, %v: %s != %s", test.peer, test.headers, w.Body.String(), test.body)
+		}
+	}
+
+	s.ProxyHeader = XForwardedHeader
+	w := httptest.NewRecorder()
+	r := dummyRequest("/moved/")
+	r.RemoteAddr, r.Header = "10.0.0.1:1234", http.Header{}
+	r.Header.Set("X-Forwarded-Proto", "https")
+	s.ServeHTTP(w, r)
+	if l := w.Header().Get("Location"); l != "https://www.juzt.in/moved/moved/to/" {
+		t.Errorf("Unexpected redirect location: %s", l)
+	}
+
+	// without a host the location is left as given
+	ctx := NewContext(httptest.NewRecorder(), dummyRequest("/a/"))
+	ctx.Request.Host = ""
+	ctx.RedirectPerm("/b/")
+	if l := ctx.Response.Header().Get("Location"); l != "/b/" {
+		t.Errorf("Unexpected redirect location without a host: %s", l)
+	}
+
+	if err := s.TrustProxies("nope"); err == nil {
+		t.Error("Invalid trusted CIDR didn't fail")
+	}
+}
@@ -0,0 +1,55 @@
+// Copyright 2013 Justin Wilson. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package dingo
+
+/*-------------------------------Error Handlers-------------------------------*/
+
+type errorRange struct {
+	from, to int
+	handler  Error
+}
+
+// errorHandlers maps statuses, and ranges of statuses, to error handlers.
+type errorHandlers struct {
+	status map[int]Error
+	ranges []errorRange
+}
+
+func (e *errorHandlers) handler(status int) Error {
+	if h, ok := e.status[status]; ok {
+		return h
+	}
+	for _, r := range e.ranges {
+		if status >= r.from && status <= r.to {
+			return r.handler
+		}
+	}
+	return ErrorHandler
+}
+
+// handle invokes the handler for the given status, falling back to the
+// global ErrorHandler.
+func (e *errorHandlers) handle(ctx Context, status int) bool {
+	if h := e.handler(status); h != nil {
+		return h(ctx, status)
+	}
+	return false
+}
+
+// HandleError sets the error handler invoked by `Context.HttpError` for the given status.
+func (s *Server) HandleError(status int, h Error) {
+	if s.errors.status == nil {
+		s.errors.status = make(map[int]Error)
+	}
+	s.errors.status[status] = h
+}
+
+// HandleErrorRange sets the error handler invoked by `Context.HttpError` for the
+// statuses between from and to, inclusive, eg. `HandleErrorRange(400, 499, h)`.
+// Handlers of a specific status take precedence over ranges, and ranges are
+// checked in the order they were added.
+func (s *Server) HandleErrorRange(from, to int, h Error) {
+	s.errors.ranges = append(s.errors.ranges, errorRange{from, to, h})
+}
@@ -0,0 +1,227 @@
+// Copyright 2013 Justin Wilson. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package dingo
+
+import (
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// ProxyHeader is the forwarding header honored from trusted proxies.
+// Only the header the proxies set may be honored, as clients can send the
+// others, which the proxies pass on unaltered.
+type ProxyHeader int
+
+const (
+	// XForwardedHeader honors the `X-Forwarded-For`, `X-Forwarded-Proto`,
+	// `X-Forwarded-Host` and `X-Real-IP` headers.
+	XForwardedHeader ProxyHeader = iota
+	// ForwardedHeader honors the `Forwarded` header.
+	ForwardedHeader
+)
+
+// proxies are the trusted proxies, and the header honored from them.
+type proxies struct {
+	trusted trustedNets
+	header  ProxyHeader
+}
+
+// TrustProxies sets the CIDRs, or IPs, of the proxies whose forwarding headers,
+// as chosen by `Server.ProxyHeader`, are honored by `Context.ClientIP`,
+// `ClientScheme` and `ClientHost`. Calling it without any CIDRs trusts no proxies.
+func (s *Server) TrustProxies(cidrs ...string) error {
+	trusted, err := parseTrusted(cidrs)
+	if err != nil {
+		return err
+	}
+	s.proxies.trusted = trusted
+	return nil
+}
+
+/*---------------------------------Forwarded----------------------------------*/
+
+// hop is a single proxy hop, as given by the forwarding headers.
+type hop struct {
+	ip, proto, host string
+}
+
+// ClientIP returns the IP of the client. When the peer is a trusted proxy,
+// it's the first untrusted address of the forwarding headers, reading from the
+// nearest proxy, otherwise it's the peer's.
+func (c *Context) ClientIP() string {
+	h, _ := c.client()
+	return h.ip
+}
+
+// ClientScheme returns the scheme, `http` or `https`, the client requested,
+// as forwarded by a trusted proxy or of the request itself.
+func (c *Context) ClientScheme() string {
+	if h, ok := c.client(); ok {
+		if p := strings.ToLower(h.proto); p == "http" || p == "https" {
+			return p
+		}
+	}
+	if c.TLS != nil {
+		return "https"
+	}
+	return "http"
+}
+
+// ClientHost returns the host the client requested, as forwarded by a trusted
+// proxy or of the request itself.
+func (c *Context) ClientHost() string {
+	if h, ok := c.client(); ok && h.host != "" {
+		return h.host
+	}
+	return c.Request.Host
+}
+
+// AbsoluteURL returns the given URL, resolved against the URL the client
+// requested, so relative paths are made absolute using the client scheme and host.
+// Without a host, such as for HTTP/1.0 requests, the URL is returned as-is.
+func (c *Context) AbsoluteURL(ref string) string {
+	u, err := url.Parse(ref)
+	if err != nil || u.IsAbs() {
+		return ref
+	}
+	host := c.ClientHost()
+	if host == "" {
+		return ref
+	}
+	base := &url.URL{Scheme: c.ClientScheme(), Host: host, Path: c.URL.Path}
+	return base.ResolveReference(u).String()
+}
+
+// client returns the hop of the client, and whether the peer is trusted.
+func (c *Context) client() (hop, bool) {
+	peer := c.RemoteAddr
+	if host, _, err := net.SplitHostPort(peer); err == nil {
+		peer = host
+	}
+	if !c.proxies.trusted.contains(peer) {
+		return hop{ip: peer}, false
+	}
+
+	var hops []hop
+	if c.proxies.header == ForwardedHeader {
+		hops = forwardedHops(c.Request.Header)
+	} else {
+		hops = xForwardedHops(c.Request.Header)
+	}
+	if len(hops) == 0 {
+		return hop{ip: peer}, true
+	}
+	h := hops[0]
+	for i := len(hops) - 1; i > 0; i-- {
+		if !c.proxies.trusted.contains(hops[i].ip) {
+			h = hops[i]
+			break
+		}
+	}
+	if h.ip == "" {
+		h.ip = peer
+	}
+	return h, true
+}
+
+// forwardedHops returns the hops of the `Forwarded` header, ordered from the client.
+func forwardedHops(header http.Header) []hop {
+	var hops []hop
+	for _, v := range header["Forwarded"] {
+		for _, elem := range splitQuoted(v, ',') {
+			var h hop
+			for _, pair := range splitQuoted(elem, ';') {
+				i := strings.IndexByte(pair, '=')
+				if i < 0 {
+					continue
+				}
+				value := strings.Trim(strings.TrimSpace(pair[i+1:]), `"`)
+				switch strings.ToLower(strings.TrimSpace(pair[:i])) {
+				case "for":
+					h.ip = nodeIP(value)
+				case "proto":
+					h.proto = value
+				case "host":
+					h.host = value
+				}
+			}
+			hops = append(hops, h)
+		}
+	}
+	return hops
+}
+
+// xForwardedHops returns the hops of the `X-Forwarded-For` header, ordered
+// from the client, or of `X-Real-IP` when absent. The `X-Forwarded-Proto` and
+// `X-Forwarded-Host` of the nearest proxy apply to every hop.
+func xForwardedHops(header http.Header) []hop {
+	var (
+		hops  []hop
+		proto = lastValue(header, "X-Forwarded-Proto")
+		host  = lastValue(header, "X-Forwarded-Host")
+	)
+	for _, v := range header["X-Forwarded-For"] {
+		for _, ip := range strings.Split(v, ",") {
+			hops = append(hops, hop{ip: nodeIP(strings.TrimSpace(ip)), proto: proto, host: host})
+		}
+	}
+	if len(hops) == 0 && (proto != "" || host != "" || header.Get("X-Real-IP") != "") {
+		h := hop{proto: proto, host: host}
+		if ip := strings.TrimSpace(header.Get("X-Real-IP")); net.ParseIP(ip) != nil {
+			h.ip = ip
+		}
+		hops = append(hops, h)
+	}
+	return hops
+}
+
+// nodeIP returns the IP of a forwarded node, removing any brackets and port.
+// Obfuscated, and unknown, nodes are returned as-is.
+func nodeIP(node string) string {
+	if strings.HasPrefix(node, "[") {
+		if i := strings.IndexByte(node, ']'); i > 0 {
+			return node[1:i]
+		}
+	} else if strings.Count(node, ":") == 1 {
+		return node[:strings.IndexByte(node, ':')]
+	}
+	return node
+}
+
+// lastValue returns the last of the comma separated values of the header,
+// being the value set by the nearest proxy.
+func lastValue(header http.Header, key string) string {
+	values := header[http.CanonicalHeaderKey(key)]
+	if len(values) == 0 {
+		return ""
+	}
+	v := values[len(values)-1]
+	return strings.TrimSpace(v[strings.LastIndexByte(v, ',')+1:])
+}
+
+// splitQuoted splits s by sep, ignoring separators within quoted strings.
+func splitQuoted(s string, sep byte) []string {
+	var (
+		parts  []string
+		quoted bool
+		start  int
+	)
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case '"':
+			quoted = !quoted
+		case '\\':
+			i++
+		case sep:
+			if !quoted {
+				parts = append(parts, s[start:i])
+				start = i + 1
+			}
+		}
+	}
+	return append(parts, s[start:])
+}
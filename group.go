@@ -0,0 +1,153 @@
+// Copyright 2013 Justin Wilson. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package dingo
+
+import (
+	"regexp"
+	"strings"
+)
+
+/*-----------------------------------Group------------------------------------*/
+
+// Group adds routes to a Server beneath a shared path prefix.
+// Routes added through a group are wrapped with the middleware, and error
+// handler, of the group and of any groups it is nested within.
+type Group struct {
+	svr        *Server
+	parent     *Group
+	prefix     string
+	middleware []Middleware
+	onError    Error
+}
+
+// Group returns a new Group used to add routes beneath the given prefix.
+func (s *Server) Group(prefix string) *Group {
+	g := new(Group)
+	g.svr = s
+	g.prefix = strings.TrimRight(prefix, "/")
+
+	return g
+}
+
+// Group returns a new Group nested within this group.
+func (g *Group) Group(prefix string) *Group {
+	n := g.svr.Group(g.path(prefix))
+	n.parent = g
+	return n
+}
+
+// Prefix returns the full path prefix of the group.
+func (g *Group) Prefix() string {
+	return g.prefix
+}
+
+// Use adds middleware invoked, in order, around every route of the group.
+// Group middleware is invoked after Server, and parent group, middleware.
+func (g *Group) Use(mw ...Middleware) {
+	g.middleware = append(g.middleware, mw...)
+}
+
+// SetErrorHandler sets the error handler used by `Context.HttpError` for the
+// routes of the group, overriding the global ErrorHandler.
+func (g *Group) SetErrorHandler(h Error) {
+	g.onError = h
+}
+
+func (g *Group) errorHandler() Error {
+	for ; g != nil; g = g.parent {
+		if g.onError != nil {
+			return g.onError
+		}
+	}
+	return nil
+}
+
+func (g *Group) chain() []Middleware {
+	if g == nil {
+		return nil
+	}
+	return append(g.parent.chain(), g.middleware...)
+}
+
+// wrap is the middleware applied to every route of the group. The chain is
+// resolved per request so middleware may be added after the routes.
+func (g *Group) wrap(next Handler) Handler {
+	return func(ctx Context) {
+		if h := g.errorHandler(); h != nil {
+			ctx.onError = h
+		}
+		Chain(next, g.chain()...)(ctx)
+	}
+}
+
+// path joins the group prefix with the given static path.
+func (g *Group) path(p string) string {
+	if len(p) == 0 || p[0] != '/' {
+		p = "/" + p
+	}
+	return g.prefix + p
+}
+
+// expr joins the group prefix with the given RegEx.
+// Unanchored expressions may match anywhere beneath the prefix.
+func (g *Group) expr(re string) string {
+	prefix := "^" + regexp.QuoteMeta(g.prefix)
+	if strings.HasPrefix(re, "^") {
+		return prefix + re[1:]
+	}
+	return prefix + ".*?" + re
+}
+
+func (g *Group) router(p string, route NewIRoute) Router {
+	r := NewRouter(g.svr, p, route)
+	r.middleware = []Middleware{g.wrap}
+	return r
+}
+
+// Route adds a route for the given methods, wrapped by the group's middleware.
+// The route's path is used as-is, without the group prefix.
+func (g *Group) Route(rt Route, methods ...string) {
+	g.svr.Route(WrapRoute(rt, g.wrap), methods...)
+}
+
+// SRoute adds a new static route beneath the group prefix.
+func (g *Group) SRoute(path string, handler Handler, methods ...string) {
+	g.Route(NewSRoute(g.path(path), handler), methods...)
+}
+
+// ReRoute adds a new RegEx route beneath the group prefix.
+func (g *Group) ReRoute(path string, handler Handler, methods ...string) {
+	g.Route(NewReRoute(g.expr(path), handler), methods...)
+}
+
+// RRoute adds a new RegEx route, beneath the group prefix, where the params are passed as params to the given handler.
+func (g *Group) RRoute(path string, handler interface{}, methods ...string) {
+	g.Route(NewRRoute(g.expr(path), handler), methods...)
+}
+
+// PRoute adds a new named param route beneath the group prefix.
+func (g *Group) PRoute(path string, handler Handler, methods ...string) {
+	g.Route(NewPRoute(g.path(path), handler), methods...)
+}
+
+// SRouter returns a new Router used to add static routes beneath the group prefix.
+func (g *Group) SRouter(p string) Router {
+	return g.router(g.path(p), iroute(NewSRoute))
+}
+
+// ReRouter returns a new Router used to add RegEx routes beneath the group prefix.
+func (g *Group) ReRouter(p string) Router {
+	return g.router(g.expr(p), iroute(NewReRoute))
+}
+
+// RRouter returns a new Router used to add RegEx routes, beneath the group prefix, where the params are passed to the handler.
+func (g *Group) RRouter(p string) Router {
+	return g.router(g.expr(p), NewRRoute)
+}
+
+// PRouter returns a new Router used to add named param routes beneath the group prefix.
+func (g *Group) PRouter(p string) Router {
+	return g.router(g.path(p), iroute(NewPRoute))
+}
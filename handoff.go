@@ -0,0 +1,219 @@
+// Copyright 2013 Justin Wilson. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// +build !appengine
+
+package dingo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+)
+
+const (
+	// listenFDsStart is the first inherited file descriptor, following stdin, stdout and stderr.
+	listenFDsStart = 3
+	// fdsEnv is the environment variable, set by Restart, holding the number of listeners passed.
+	fdsEnv = "DINGO_FDS"
+)
+
+var (
+	inheritOnce sync.Once
+	inheritMu   sync.Mutex
+	inherited   []net.Listener
+	// restarted is whether the inherited listeners were passed by Restart,
+	// rather than by systemd, so are owned by this process.
+	restarted bool
+)
+
+/*---------------------------------Inherited----------------------------------*/
+
+// loadInherited creates listeners from the file descriptors passed by a parent
+// process, either by systemd socket activation (`LISTEN_FDS`) or by Restart.
+func loadInherited() {
+	n, err := strconv.Atoi(os.Getenv(fdsEnv))
+	restarted = err == nil
+	if err != nil {
+		if pid, err := strconv.Atoi(os.Getenv("LISTEN_PID")); err != nil || pid != os.Getpid() {
+			return
+		}
+		if n, err = strconv.Atoi(os.Getenv("LISTEN_FDS")); err != nil {
+			return
+		}
+	}
+	for _, env := range []string{fdsEnv, "LISTEN_PID", "LISTEN_FDS", "LISTEN_FDNAMES"} {
+		os.Unsetenv(env)
+	}
+
+	for fd := listenFDsStart; fd < listenFDsStart+n; fd++ {
+		syscall.CloseOnExec(fd)
+		f := os.NewFile(uintptr(fd), fmt.Sprint("listener-", fd))
+		l, err := net.FileListener(f)
+		f.Close()
+		if err != nil {
+			log.Printf("dingo: failed to inherit listener, fd %d, %s\n", fd, err)
+			continue
+		}
+		inherited = append(inherited, l)
+	}
+}
+
+// InheritedListeners returns the listeners passed by a parent process which
+// haven't been claimed by `HTTPListener`, `TLSListener` or `SOCKListener`.
+// The returned listeners are claimed by the caller.
+func InheritedListeners() []net.Listener {
+	inheritOnce.Do(loadInherited)
+	inheritMu.Lock()
+	defer inheritMu.Unlock()
+
+	l := inherited
+	inherited = nil
+	return l
+}
+
+// inherit claims the inherited listener with the given network address.
+func inherit(network, addr string) (net.Listener, bool) {
+	inheritOnce.Do(loadInherited)
+	inheritMu.Lock()
+	defer inheritMu.Unlock()
+
+	for i, l := range inherited {
+		if sameAddr(network, addr, l.Addr()) {
+			inherited = append(inherited[:i], inherited[i+1:]...)
+			return l, true
+		}
+	}
+	return nil, false
+}
+
+func sameAddr(network, addr string, a net.Addr) bool {
+	switch la := a.(type) {
+	case *net.UnixAddr:
+		return network == "unix" && la.Name == addr
+	case *net.TCPAddr:
+		if network != "tcp" {
+			return false
+		}
+		ta, err := net.ResolveTCPAddr(network, addr)
+		if err != nil || ta.Port != la.Port {
+			return false
+		}
+		unspecified := (ta.IP == nil || ta.IP.IsUnspecified()) && (la.IP == nil || la.IP.IsUnspecified())
+		return unspecified || ta.IP.Equal(la.IP)
+	}
+	return false
+}
+
+/*----------------------------------Restart-----------------------------------*/
+
+// baseListener returns the innermost listener of the given listener.
+func baseListener(l net.Listener) net.Listener {
+	for {
+		w, ok := l.(listenerWrapper)
+		if !ok {
+			return l
+		}
+		l = w.unwrapListener()
+	}
+}
+
+// listenerFile returns a duplicate file of the socket underlying the listener.
+func listenerFile(l net.Listener) (*os.File, error) {
+	switch t := baseListener(l).(type) {
+	case *net.TCPListener:
+		return t.File()
+	case *net.UnixListener:
+		return t.File()
+	}
+	return nil, fmt.Errorf("dingo: can't pass listener %T", l)
+}
+
+// listenerWrapper is implemented by listeners wrapping another listener.
+type listenerWrapper interface {
+	unwrapListener() net.Listener
+}
+
+// Restart starts a new copy of the running binary, with the same args and
+// environment, passing it the given listeners. The new process takes the
+// listeners over by calling `HTTPListener`, `TLSListener` or `SOCKListener`
+// with the same address, or `InheritedListeners`.
+func Restart(listeners ...net.Listener) (*os.Process, error) {
+	if len(listeners) == 0 {
+		return nil, errors.New("dingo: no listeners to pass")
+	}
+	path, err := os.Executable()
+	if err != nil {
+		return nil, err
+	}
+
+	files := []*os.File{os.Stdin, os.Stdout, os.Stderr}
+	for _, l := range listeners {
+		f, err := listenerFile(l)
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+		files = append(files, f)
+	}
+
+	var env []string
+	for _, e := range os.Environ() {
+		if !strings.HasPrefix(e, fdsEnv+"=") && !strings.HasPrefix(e, "LISTEN_") {
+			env = append(env, e)
+		}
+	}
+	env = append(env, fmt.Sprint(fdsEnv, "=", len(listeners)))
+
+	p, err := os.StartProcess(path, os.Args, &os.ProcAttr{Env: env, Files: files})
+	if err != nil {
+		return nil, err
+	}
+	for _, l := range listeners {
+		if ul, ok := baseListener(l).(*net.UnixListener); ok {
+			// the new process owns the socket file now
+			ul.SetUnlinkOnClose(false)
+		}
+	}
+	return p, nil
+}
+
+// RestartOnSignal restarts the binary upon receiving any of the given signals,
+// defaulting to SIGHUP, passing it the server's listeners, then shuts the
+// server down allowing in-flight requests the given timeout to finish.
+func (s *Server) RestartOnSignal(timeout time.Duration, sigs ...os.Signal) {
+	if len(sigs) == 0 {
+		sigs = []os.Signal{syscall.SIGHUP}
+	}
+	c := make(chan os.Signal, 1)
+	signal.Notify(c, sigs...)
+
+	go func() {
+		for sig := range c {
+			p, err := Restart(s.listeners...)
+			if err != nil {
+				log.Printf("dingo: received %s, restart failed, %s\n", sig, err)
+				continue
+			}
+			signal.Stop(c)
+			log.Printf("dingo: received %s, restarted as pid %d, shutting down\n", sig, p.Pid)
+
+			ctx, cancel := context.WithTimeout(context.Background(), timeout)
+			if err := s.Shutdown(ctx); err != nil {
+				log.Println("dingo: shutdown error, ", err)
+			}
+			cancel()
+			return
+		}
+	}()
+}
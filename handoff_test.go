@@ -0,0 +1,209 @@
+// Copyright 2013 Justin Wilson. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// +build !appengine
+
+package dingo
+
+import (
+	"context"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+)
+
+const (
+	handoffTCPEnv  = "DINGO_TEST_TCP"
+	handoffSockEnv = "DINGO_TEST_SOCK"
+	handoffRun     = "-test.run=^TestHandoffChild$"
+)
+
+/*-------------------------Helpers-------------------------*/
+type handoff struct {
+	dir       string
+	sock      string
+	tcp, unix net.Listener
+}
+
+func newHandoff(t *testing.T) *handoff {
+	dir, err := ioutil.TempDir("", "dingo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	h := &handoff{dir: dir, sock: filepath.Join(dir, "handoff.sock")}
+	if h.tcp, err = HTTPListener("127.0.0.1", 0); err != nil {
+		t.Fatal(err)
+	}
+	if h.unix, err = SOCKListener(h.sock, 0600); err != nil {
+		t.Fatal(err)
+	}
+	return h
+}
+
+func (h *handoff) env() []string {
+	return []string{handoffTCPEnv + "=" + h.tcp.Addr().String(), handoffSockEnv + "=" + h.sock}
+}
+
+func (h *handoff) files(t *testing.T) []*os.File {
+	var files []*os.File
+	for _, l := range []net.Listener{h.tcp, h.unix} {
+		f, err := listenerFile(l)
+		if err != nil {
+			t.Fatal(err)
+		}
+		files = append(files, f)
+	}
+	return files
+}
+
+func (h *handoff) close() {
+	h.tcp.Close()
+	h.unix.Close()
+	os.RemoveAll(h.dir)
+}
+
+// served asserts both listeners are served by the process with the given pid.
+// The listeners are never accepted by this process, so only a child which
+// inherited them can respond.
+func (h *handoff) served(t *testing.T, pid int) {
+	clients := map[string]*http.Client{
+		"tcp": {Timeout: 5 * time.Second},
+		"unix": {Timeout: 5 * time.Second, Transport: &http.Transport{
+			DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+				return net.Dial("unix", h.sock)
+			},
+		}},
+	}
+	for name, c := range clients {
+		res, err := c.Get("http://" + h.tcp.Addr().String() + "/pid/")
+		if err != nil {
+			t.Errorf("Inherited %s listener wasn't served: %s", name, err)
+			continue
+		}
+		b, _ := ioutil.ReadAll(res.Body)
+		res.Body.Close()
+		if string(b) != strconv.Itoa(pid) {
+			t.Errorf("Inherited %s listener served by unexpected process: %s != %d", name, b, pid)
+		}
+	}
+}
+
+/*-------------------------Tests-------------------------*/
+
+// TestHandoffChild is run by the handoff tests as the child process, claiming
+// the inherited listeners and serving them until killed.
+func TestHandoffChild(t *testing.T) {
+	addr, sock := os.Getenv(handoffTCPEnv), os.Getenv(handoffSockEnv)
+	if addr == "" {
+		t.Skip("only run as a child process of the handoff tests")
+	}
+
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p, _ := strconv.Atoi(port)
+	tcp, err := HTTPListener(host, p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	unix, err := SOCKListener(sock, 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if l := InheritedListeners(); len(l) != 0 {
+		t.Fatalf("Unclaimed inherited listeners: %v", l)
+	}
+
+	s := New(tcp, unix)
+	s.SRoute("/pid/", func(ctx Context) { wc(ctx, strconv.Itoa(os.Getpid())) }, "GET")
+	t.Fatal(s.Serve())
+}
+
+func TestRestart(t *testing.T) {
+	h := newHandoff(t)
+	defer h.close()
+
+	// run only the child test within the restarted copy of the test binary
+	args := os.Args
+	os.Args = []string{args[0], handoffRun}
+	defer func() { os.Args = args }()
+	for _, e := range []string{handoffTCPEnv, handoffSockEnv} {
+		defer os.Unsetenv(e)
+	}
+	os.Setenv(handoffTCPEnv, h.tcp.Addr().String())
+	os.Setenv(handoffSockEnv, h.sock)
+
+	p, err := Restart(h.tcp, h.unix)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer p.Wait()
+	defer p.Kill()
+
+	h.served(t, p.Pid)
+}
+
+func TestSystemdInherit(t *testing.T) {
+	h := newHandoff(t)
+	defer h.close()
+
+	// LISTEN_PID must be the pid of the child, which the shell keeps on exec
+	cmd := exec.Command("/bin/sh", "-c", `LISTEN_PID=$$ LISTEN_FDS=2 exec "$0" "$@"`, os.Args[0], handoffRun)
+	cmd.Env = append(os.Environ(), h.env()...)
+	cmd.Stdout, cmd.Stderr = os.Stdout, os.Stderr
+	cmd.ExtraFiles = h.files(t)
+	if err := cmd.Start(); err != nil {
+		t.Fatal(err)
+	}
+	for _, f := range cmd.ExtraFiles {
+		f.Close()
+	}
+	defer cmd.Wait()
+	defer cmd.Process.Kill()
+
+	h.served(t, cmd.Process.Pid)
+}
+
+func TestRestartFailure(t *testing.T) {
+	h := newHandoff(t)
+	defer h.close()
+
+	// a listener which can't be passed fails the restart before it starts
+	if _, err := Restart(h.unix, dummyListener{h.tcp}); err == nil {
+		t.Fatal("Restart with an unpassable listener didn't fail")
+	}
+	h.unix.Close()
+	if _, err := os.Stat(h.sock); !os.IsNotExist(err) {
+		t.Errorf("Sock file wasn't removed after a failed restart: %v", err)
+	}
+}
+
+func TestInheritedAddr(t *testing.T) {
+	tcp := &net.TCPAddr{IP: net.IPv6unspecified, Port: 8000}
+	if !sameAddr("tcp", "0.0.0.0:8000", tcp) || sameAddr("tcp", "0.0.0.0:8001", tcp) || sameAddr("unix", "0.0.0.0:8000", tcp) {
+		t.Error("Unexpected unspecified tcp address match")
+	}
+
+	local := &net.TCPAddr{IP: net.IPv4(127, 0, 0, 1), Port: 8000}
+	if !sameAddr("tcp", "127.0.0.1:8000", local) || sameAddr("tcp", "10.0.0.1:8000", local) {
+		t.Error("Unexpected tcp address match")
+	}
+
+	sock := &net.UnixAddr{Name: "/tmp/dingo.sock", Net: "unix"}
+	if !sameAddr("unix", "/tmp/dingo.sock", sock) || sameAddr("unix", "/tmp/other.sock", sock) {
+		t.Error("Unexpected unix address match")
+	}
+}
+
+// dummyListener is a listener which can't be passed to a new process.
+type dummyListener struct {
+	net.Listener
+}
@@ -4,3 +4,5 @@ go install code.minty.io/dingo
 go install code.minty.io/dingo/views
 go install code.minty.io/dingo/request
 go install code.minty.io/dingo/rest
+go install code.minty.io/dingo/bind
+go install code.minty.io/dingo/validate
@@ -0,0 +1,323 @@
+// Copyright 2013 Justin Wilson. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// +build !appengine
+
+package dingo
+
+import (
+	"errors"
+	"math"
+	"net"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+var errLimitClosed = errors.New("dingo: limited listener closed")
+
+// LimitOptions configures the limits of a `Limiter`, zero values are unlimited.
+type LimitOptions struct {
+	// MaxConns is the maximum number of concurrent connections, once reached
+	// Accept blocks until a connection is closed.
+	MaxConns int
+	// MaxConnsPerIP is the maximum number of concurrent connections from a
+	// single remote IP, further connections are closed as they're accepted.
+	MaxConnsPerIP int
+	// Rate is the number of connections accepted per second, in bursts of up
+	// to Burst, once exceeded Accept is delayed.
+	Rate  float64
+	Burst int
+	// PerIPRate is the number of connections accepted per second from a single
+	// remote IP, in bursts of up to PerIPBurst, further connections are closed
+	// as they're accepted.
+	PerIPRate  float64
+	PerIPBurst int
+}
+
+// LimitStats are the counters of a `Limiter`.
+type LimitStats struct {
+	Active        int64 // connections currently open
+	Accepted      int64 // connections accepted
+	Throttled     int64 // accepts delayed by MaxConns or Rate
+	RejectedConns int64 // connections closed by MaxConnsPerIP
+	RejectedRate  int64 // connections closed by PerIPRate
+}
+
+/*----------------------------------Limiter-----------------------------------*/
+
+// Limiter limits the connections of the listeners it wraps. The limits are
+// shared by every listener wrapped by the same limiter.
+type Limiter struct {
+	stats  LimitStats
+	opts   LimitOptions
+	slots  chan struct{}
+	mu     sync.Mutex
+	rate   *bucket
+	ips    map[string]*ipLimit
+	pruned time.Time
+}
+
+type ipLimit struct {
+	conns int
+	rate  *bucket
+}
+
+// NewLimiter returns a new Limiter for the given options.
+func NewLimiter(opts LimitOptions) *Limiter {
+	lim := new(Limiter)
+	lim.opts = opts
+	lim.ips = make(map[string]*ipLimit)
+	lim.pruned = time.Now()
+	if opts.MaxConns > 0 {
+		lim.slots = make(chan struct{}, opts.MaxConns)
+	}
+	if opts.Rate > 0 {
+		lim.rate = newBucket(opts.Rate, opts.Burst)
+	}
+
+	return lim
+}
+
+// Listener returns the given listener limited by the limiter.
+// TLS listeners are limited beneath the TLS handshake, so served connections
+// remain TLS connections. Listeners returned by `ProxyListener` are limited
+// beneath the PROXY header, so Accept isn't blocked reading it, and the per-IP
+// limits see the address of the balancer rather than of the client.
+func (lim *Limiter) Listener(l net.Listener) net.Listener {
+	switch t := l.(type) {
+	case *tlsListener:
+		c := *t
+		c.Listener = lim.Listener(t.Listener)
+		return &c
+	case *proxyListener:
+		c := *t
+		c.Listener = lim.Listener(t.Listener)
+		return &c
+	}
+	return &limitListener{Listener: l, lim: lim, done: make(chan struct{})}
+}
+
+// Stats returns the current counters of the limiter.
+func (lim *Limiter) Stats() LimitStats {
+	return LimitStats{
+		Active:        atomic.LoadInt64(&lim.stats.Active),
+		Accepted:      atomic.LoadInt64(&lim.stats.Accepted),
+		Throttled:     atomic.LoadInt64(&lim.stats.Throttled),
+		RejectedConns: atomic.LoadInt64(&lim.stats.RejectedConns),
+		RejectedRate:  atomic.LoadInt64(&lim.stats.RejectedRate),
+	}
+}
+
+// wait blocks until a connection slot, and rate token, is available.
+func (lim *Limiter) wait(done <-chan struct{}) error {
+	throttled := false
+	if lim.slots != nil {
+		select {
+		case lim.slots <- struct{}{}:
+		default:
+			throttled = true
+			select {
+			case lim.slots <- struct{}{}:
+			case <-done:
+				return errLimitClosed
+			}
+		}
+	}
+
+	for lim.rate != nil {
+		lim.mu.Lock()
+		d := lim.rate.take(time.Now())
+		lim.mu.Unlock()
+		if d == 0 {
+			break
+		}
+
+		throttled = true
+		t := time.NewTimer(d)
+		select {
+		case <-t.C:
+		case <-done:
+			t.Stop()
+			lim.free()
+			return errLimitClosed
+		}
+	}
+
+	if throttled {
+		atomic.AddInt64(&lim.stats.Throttled, 1)
+	}
+	return nil
+}
+
+// admit records a connection from the given ip, returning false when it
+// exceeds the per-IP limits.
+func (lim *Limiter) admit(ip string) bool {
+	lim.mu.Lock()
+	defer lim.mu.Unlock()
+
+	now := time.Now()
+	lim.prune(now)
+	if ip != "" {
+		e := lim.ips[ip]
+		if e == nil {
+			e = new(ipLimit)
+			if lim.opts.PerIPRate > 0 {
+				e.rate = newBucket(lim.opts.PerIPRate, lim.opts.PerIPBurst)
+			}
+			lim.ips[ip] = e
+		}
+		if lim.opts.MaxConnsPerIP > 0 && e.conns >= lim.opts.MaxConnsPerIP {
+			atomic.AddInt64(&lim.stats.RejectedConns, 1)
+			lim.free()
+			return false
+		}
+		if e.rate != nil && e.rate.take(now) > 0 {
+			atomic.AddInt64(&lim.stats.RejectedRate, 1)
+			lim.free()
+			return false
+		}
+		e.conns++
+	}
+
+	atomic.AddInt64(&lim.stats.Accepted, 1)
+	atomic.AddInt64(&lim.stats.Active, 1)
+	return true
+}
+
+// release records the close of a connection from the given ip.
+func (lim *Limiter) release(ip string) {
+	lim.mu.Lock()
+	if e := lim.ips[ip]; e != nil {
+		if e.conns--; e.conns == 0 && e.rate == nil {
+			delete(lim.ips, ip)
+		}
+	}
+	lim.mu.Unlock()
+
+	atomic.AddInt64(&lim.stats.Active, -1)
+	lim.free()
+}
+
+// free releases a connection slot.
+func (lim *Limiter) free() {
+	if lim.slots != nil {
+		<-lim.slots
+	}
+}
+
+// prune removes, at most once a minute, the IPs without connections whose
+// rate limit has recovered.
+func (lim *Limiter) prune(now time.Time) {
+	if now.Sub(lim.pruned) < time.Minute {
+		return
+	}
+	lim.pruned = now
+	for ip, e := range lim.ips {
+		if e.conns == 0 && (e.rate == nil || e.rate.full(now)) {
+			delete(lim.ips, ip)
+		}
+	}
+}
+
+/*-----------------------------------Bucket-----------------------------------*/
+
+// bucket is a token bucket, refilled at rate tokens per second up to burst.
+type bucket struct {
+	rate, burst, tokens float64
+	last                time.Time
+}
+
+func newBucket(rate float64, burst int) *bucket {
+	if burst < 1 {
+		burst = 1
+	}
+	return &bucket{rate: rate, burst: float64(burst), tokens: float64(burst), last: time.Now()}
+}
+
+func (b *bucket) fill(now time.Time) {
+	b.tokens = math.Min(b.burst, b.tokens+now.Sub(b.last).Seconds()*b.rate)
+	b.last = now
+}
+
+// take removes a token, returning how long until one is available when empty.
+func (b *bucket) take(now time.Time) time.Duration {
+	b.fill(now)
+	if b.tokens >= 1 {
+		b.tokens--
+		return 0
+	}
+	return time.Duration((1 - b.tokens) / b.rate * float64(time.Second))
+}
+
+func (b *bucket) full(now time.Time) bool {
+	b.fill(now)
+	return b.tokens >= b.burst
+}
+
+/*------------------------------Limited Listener------------------------------*/
+
+type limitListener struct {
+	net.Listener
+	lim  *Limiter
+	done chan struct{}
+	once sync.Once
+}
+
+// Accept waits for, and returns, the next connection within the limits.
+func (l *limitListener) Accept() (net.Conn, error) {
+	for {
+		if err := l.lim.wait(l.done); err != nil {
+			return nil, err
+		}
+		c, err := l.Listener.Accept()
+		if err != nil {
+			l.lim.free()
+			return nil, err
+		}
+
+		ip := remoteIP(c)
+		if !l.lim.admit(ip) {
+			c.Close()
+			continue
+		}
+		return &limitConn{Conn: c, lim: l.lim, ip: ip}, nil
+	}
+}
+
+// Close closes the listener, unblocking any waiting Accept.
+func (l *limitListener) Close() error {
+	l.once.Do(func() { close(l.done) })
+	return l.Listener.Close()
+}
+
+func (l *limitListener) unwrapListener() net.Listener {
+	return l.Listener
+}
+
+type limitConn struct {
+	net.Conn
+	lim  *Limiter
+	ip   string
+	once sync.Once
+}
+
+// Close closes the connection, releasing it from the limits.
+func (c *limitConn) Close() error {
+	c.once.Do(func() { c.lim.release(c.ip) })
+	return c.Conn.Close()
+}
+
+// remoteIP returns the IP of the connection, or empty for non IP connections.
+func remoteIP(c net.Conn) string {
+	addr := c.RemoteAddr()
+	if addr == nil {
+		return ""
+	}
+	host, _, err := net.SplitHostPort(addr.String())
+	if err != nil {
+		return ""
+	}
+	return host
+}
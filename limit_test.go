@@ -0,0 +1,171 @@
+// Copyright 2013 Justin Wilson. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// +build !appengine
+
+package dingo
+
+import (
+	"net"
+	"testing"
+	"time"
+)
+
+/*-------------------------Helpers-------------------------*/
+func limitedListener(t *testing.T, opts LimitOptions) (*Limiter, net.Listener, chan net.Conn) {
+	l, err := HTTPListener("127.0.0.1", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lim := NewLimiter(opts)
+	ll := lim.Listener(l)
+
+	accepted := make(chan net.Conn, 10)
+	go func() {
+		for {
+			c, err := ll.Accept()
+			if err != nil {
+				close(accepted)
+				return
+			}
+			accepted <- c
+		}
+	}()
+	return lim, ll, accepted
+}
+
+func dial(t *testing.T, l net.Listener) net.Conn {
+	c, err := net.Dial("tcp", l.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return c
+}
+
+func accepted(conns chan net.Conn, wait time.Duration) net.Conn {
+	select {
+	case c := <-conns:
+		return c
+	case <-time.After(wait):
+		return nil
+	}
+}
+
+/*-------------------------Tests-------------------------*/
+func TestLimitMaxConns(t *testing.T) {
+	lim, l, conns := limitedListener(t, LimitOptions{MaxConns: 1})
+	defer l.Close()
+
+	defer dial(t, l).Close()
+	first := accepted(conns, time.Second)
+	if first == nil {
+		t.Fatal("First connection wasn't accepted")
+	}
+	defer dial(t, l).Close()
+	if c := accepted(conns, 50*time.Millisecond); c != nil {
+		t.Fatal("Connection accepted beyond MaxConns")
+	}
+
+	first.Close()
+	if c := accepted(conns, time.Second); c == nil {
+		t.Error("Connection wasn't accepted once a slot was released")
+	}
+	if s := lim.Stats(); s.Accepted != 2 || s.Active != 1 || s.Throttled != 1 {
+		t.Errorf("Unexpected stats: %+v", s)
+	}
+}
+
+func TestLimitPerIP(t *testing.T) {
+	lim, l, conns := limitedListener(t, LimitOptions{MaxConnsPerIP: 1, PerIPRate: 0.1, PerIPBurst: 2})
+	defer l.Close()
+
+	defer dial(t, l).Close()
+	first := accepted(conns, time.Second)
+	if first == nil {
+		t.Fatal("First connection wasn't accepted")
+	}
+	defer dial(t, l).Close()
+	if c := accepted(conns, 50*time.Millisecond); c != nil {
+		t.Error("Connection accepted beyond MaxConnsPerIP")
+	}
+
+	first.Close()
+	defer dial(t, l).Close()
+	second := accepted(conns, time.Second)
+	if second == nil {
+		t.Fatal("Connection wasn't accepted once the first was closed")
+	}
+
+	second.Close()
+	defer dial(t, l).Close()
+	if c := accepted(conns, 50*time.Millisecond); c != nil {
+		t.Error("Connection accepted beyond PerIPRate")
+	}
+	if s := lim.Stats(); s.Accepted != 2 || s.Active != 0 || s.RejectedConns != 1 || s.RejectedRate != 1 {
+		t.Errorf("Unexpected stats: %+v", s)
+	}
+}
+
+func TestLimitRate(t *testing.T) {
+	lim, l, conns := limitedListener(t, LimitOptions{Rate: 20, Burst: 1})
+	defer l.Close()
+
+	start := time.Now()
+	for i := 0; i < 3; i++ {
+		defer dial(t, l).Close()
+		if accepted(conns, time.Second) == nil {
+			t.Fatal("Connection wasn't accepted")
+		}
+	}
+	if d := time.Since(start); d < 80*time.Millisecond {
+		t.Errorf("Accepts weren't rate limited: %s", d)
+	}
+	if s := lim.Stats(); s.Accepted != 3 || s.Throttled != 2 {
+		t.Errorf("Unexpected stats: %+v", s)
+	}
+}
+
+func TestLimitProxy(t *testing.T) {
+	l, err := HTTPListener("127.0.0.1", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pl, err := ProxyListener(l, ProxyOptions{Trusted: []string{"127.0.0.1"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	lim := NewLimiter(LimitOptions{MaxConnsPerIP: 10})
+	ll := lim.Listener(pl)
+	defer ll.Close()
+
+	conns := make(chan net.Conn, 2)
+	go func() {
+		for {
+			c, err := ll.Accept()
+			if err != nil {
+				return
+			}
+			conns <- c
+		}
+	}()
+
+	// a connection yet to send it's header doesn't block accepting others
+	defer dial(t, l).Close()
+	if accepted(conns, time.Second) == nil {
+		t.Fatal("Idle connection wasn't accepted")
+	}
+	c := dial(t, l)
+	defer c.Close()
+	c.Write([]byte("PROXY TCP4 203.0.113.7 198.51.100.1 4321 443\r\n"))
+	pc := accepted(conns, time.Second)
+	if pc == nil {
+		t.Fatal("Connection was blocked by the idle connection")
+	}
+	if addr := pc.RemoteAddr().String(); addr != "203.0.113.7:4321" {
+		t.Errorf("Unexpected remote address: %s", addr)
+	}
+	if s := lim.Stats(); s.Accepted != 2 || s.Active != 2 {
+		t.Errorf("Unexpected stats: %+v", s)
+	}
+}
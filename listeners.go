@@ -7,53 +7,166 @@
 package dingo
 
 import (
-	"crypto/tls"
+	"errors"
 	"fmt"
 	"net"
 	"os"
+	"os/user"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
 )
 
-// HTTPListener returns a new net.Listener for the given ip/port
+// HTTPListener returns a new net.Listener for the given ip/port, or the listener
+// inherited from a parent process for the same ip/port.
 func HTTPListener(ip string, port int) (net.Listener, error) {
 	addr := fmt.Sprint(ip, ":", port)
+	if l, ok := inherit("tcp", addr); ok {
+		return l, nil
+	}
 	return net.Listen("tcp", addr)
 }
 
-// TLSListener returns a new net.Listener for the given ip/port and cert
+// TLSListener returns a new net.Listener for the given ip/port and cert, using
+// the listener inherited from a parent process for the same ip/port.
 func TLSListener(ip string, port int, certFile, keyFile string) (net.Listener, error) {
+	return TLSOptionsListener(ip, port, TLSOptions{Certs: []Cert{{certFile, keyFile}}})
+}
+
+// TLSOptionsListener returns a new net.Listener for the given ip/port and
+// options, using the listener inherited from a parent process for the same ip/port.
+func TLSOptionsListener(ip string, port int, opts TLSOptions) (net.Listener, error) {
 	// this func is based off of Go source `net/http - server.go`
 	addr := fmt.Sprint(ip, ":", port)
-	config := &tls.Config{NextProtos: []string{"http/1.1"}}
-
-	var err error
-	config.Certificates = make([]tls.Certificate, 1)
-	config.Certificates[0], err = tls.LoadX509KeyPair(certFile, keyFile)
+	config, store, err := opts.config()
 	if err != nil {
 		return nil, err
 	}
 
-	conn, err := net.Listen("tcp", addr)
-	if err != nil {
-		return conn, err
+	conn, ok := inherit("tcp", addr)
+	if !ok {
+		if conn, err = net.Listen("tcp", addr); err != nil {
+			store.stop()
+			return conn, err
+		}
 	}
 
-	return tls.NewListener(conn, config), nil
+	return &tlsListener{Listener: conn, config: config, store: store}, nil
+}
+
+// SOCKOptions configures the listener returned by `SOCKOptionsListener`.
+type SOCKOptions struct {
+	// Mode is the file mode of the sock file, defaulting to 0660 when zero,
+	// as a sock nobody can connect to is never intended.
+	Mode os.FileMode
+	// User and Group, names or numeric ids, own the sock file when set.
+	User, Group string
 }
 
-// SOCKListener returns a new unix sock listener.
+// SOCKListener returns a new unix sock listener, or the listener inherited
+// from a parent process for the same sock file.
 func SOCKListener(sockFile string, mode os.FileMode) (net.Listener, error) {
-	// delete stale sock
-	// TODO check errors other than file doesn't exist
-	os.Remove(sockFile)
+	return SOCKOptionsListener(sockFile, SOCKOptions{Mode: mode})
+}
+
+// SOCKOptionsListener returns a new unix sock listener for the given options,
+// or the listener inherited from a parent process for the same sock file.
+//
+// An existing sock file is only removed when no process is listening on it,
+// and any other file is never removed. The sock file is removed once the
+// listener is closed. Sock files beginning with `@` are Linux abstract sockets,
+// having no file, so the mode and owner are ignored.
+func SOCKOptionsListener(sockFile string, opts SOCKOptions) (net.Listener, error) {
+	if l, ok := inherit("unix", sockFile); ok {
+		if ul, ok := l.(*net.UnixListener); ok && restarted {
+			ul.SetUnlinkOnClose(true)
+		}
+		return l, nil
+	}
+
+	abstract := strings.HasPrefix(sockFile, "@")
+	if !abstract {
+		if err := removeStaleSock(sockFile); err != nil {
+			return nil, err
+		}
+	}
 
 	// create UNIX sock
 	sock, err := net.ResolveUnixAddr("unix", sockFile)
 	if err != nil {
 		return nil, err
 	}
-	if l, err := net.ListenUnix("unix", sock); err == nil {
-		err = os.Chmod(sockFile, mode)
+	l, err := net.ListenUnix("unix", sock)
+	if err != nil || abstract {
 		return l, err
 	}
-	return nil, err
+
+	mode := opts.Mode
+	if mode == 0 {
+		mode = 0660
+	}
+	if err = os.Chmod(sockFile, mode); err == nil {
+		err = chownSock(sockFile, opts.User, opts.Group)
+	}
+	if err != nil {
+		l.Close()
+		return nil, err
+	}
+	return l, nil
+}
+
+// removeStaleSock removes the sock file when no process is listening on it.
+func removeStaleSock(sockFile string) error {
+	fi, err := os.Lstat(sockFile)
+	if os.IsNotExist(err) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	if fi.Mode()&os.ModeSocket == 0 {
+		return fmt.Errorf("dingo: %s exists and isn't a sock file", sockFile)
+	}
+
+	c, err := net.DialTimeout("unix", sockFile, time.Second)
+	if err == nil {
+		c.Close()
+		return fmt.Errorf("dingo: %s is in use by another process", sockFile)
+	}
+	if !errors.Is(err, syscall.ECONNREFUSED) {
+		return err
+	}
+	return os.Remove(sockFile)
+}
+
+// chownSock changes the owner of the sock file to the given user and group.
+func chownSock(sockFile, usr, group string) error {
+	if usr == "" && group == "" {
+		return nil
+	}
+
+	uid, gid := -1, -1
+	if usr != "" {
+		id, err := strconv.Atoi(usr)
+		if err != nil {
+			u, err := user.Lookup(usr)
+			if err != nil {
+				return err
+			}
+			id, _ = strconv.Atoi(u.Uid)
+		}
+		uid = id
+	}
+	if group != "" {
+		id, err := strconv.Atoi(group)
+		if err != nil {
+			g, err := user.LookupGroup(group)
+			if err != nil {
+				return err
+			}
+			id, _ = strconv.Atoi(g.Gid)
+		}
+		gid = id
+	}
+	return os.Chown(sockFile, uid, gid)
 }
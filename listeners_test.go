@@ -0,0 +1,118 @@
+// Copyright 2013 Justin Wilson. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// +build !appengine
+
+package dingo
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+)
+
+/*-------------------------Tests-------------------------*/
+func TestSOCKListenerStale(t *testing.T) {
+	dir, err := ioutil.TempDir("", "dingo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	sock := filepath.Join(dir, "stale.sock")
+
+	stale, err := net.ListenUnix("unix", &net.UnixAddr{Name: sock, Net: "unix"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	stale.SetUnlinkOnClose(false)
+	stale.Close()
+
+	l, err := SOCKListener(sock, 0600)
+	if err != nil {
+		t.Fatalf("Stale sock wasn't replaced: %s", err)
+	}
+	if fi, err := os.Stat(sock); err != nil || fi.Mode().Perm() != 0600 {
+		t.Errorf("Unexpected sock file: %v, %v", fi, err)
+	}
+
+	if _, err := SOCKListener(sock, 0600); err == nil {
+		t.Error("Sock in use by a live listener was replaced")
+	}
+
+	l.Close()
+	if _, err := os.Stat(sock); !os.IsNotExist(err) {
+		t.Errorf("Sock file wasn't removed on close: %v", err)
+	}
+}
+
+func TestSOCKListenerNonSock(t *testing.T) {
+	dir, err := ioutil.TempDir("", "dingo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	file := filepath.Join(dir, "file.sock")
+	if err := ioutil.WriteFile(file, []byte("data"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := SOCKListener(file, 0600); err == nil {
+		t.Error("Non sock file was replaced")
+	}
+	if b, err := ioutil.ReadFile(file); err != nil || string(b) != "data" {
+		t.Errorf("Non sock file was modified: %s, %v", b, err)
+	}
+}
+
+func TestSOCKListenerOwner(t *testing.T) {
+	dir, err := ioutil.TempDir("", "dingo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	sock := filepath.Join(dir, "owned.sock")
+	l, err := SOCKOptionsListener(sock, SOCKOptions{User: fmt.Sprint(os.Getuid()), Group: fmt.Sprint(os.Getgid())})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fi, err := os.Stat(sock); err != nil || fi.Mode().Perm() != 0660 {
+		t.Errorf("Sock file didn't default to mode 0660: %v, %v", fi, err)
+	}
+	l.Close()
+
+	if _, err := SOCKOptionsListener(sock, SOCKOptions{Mode: 0660, User: "no-such-dingo-user"}); err == nil {
+		t.Error("Unknown user didn't fail")
+	}
+	if _, err := os.Stat(sock); !os.IsNotExist(err) {
+		t.Errorf("Sock file wasn't removed after failing: %v", err)
+	}
+}
+
+func TestSOCKListenerAbstract(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("abstract sockets are Linux only")
+	}
+	name := fmt.Sprintf("@dingo-test-%d", os.Getpid())
+	l, err := SOCKListener(name, 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+
+	go func() {
+		if c, err := l.Accept(); err == nil {
+			c.Close()
+		}
+	}()
+	c, err := net.Dial("unix", name)
+	if err != nil {
+		t.Fatalf("Abstract sock wasn't dialable: %s", err)
+	}
+	c.Close()
+}
@@ -0,0 +1,71 @@
+// Copyright 2013 Justin Wilson. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package dingo
+
+/*--------------------------------Middleware----------------------------------*/
+
+// Middleware wraps a Handler, returning a new Handler.
+// The returned handler should invoke `next` to continue the chain, having access
+// to the Context both before and after, or return without invoking it to
+// short-circuit the remaining middleware and the route.
+type Middleware func(next Handler) Handler
+
+// Chain composes the given middleware around the handler.
+// The first middleware is the outermost, so it is invoked first.
+func Chain(h Handler, mw ...Middleware) Handler {
+	for i := len(mw) - 1; i >= 0; i-- {
+		h = mw[i](h)
+	}
+	return h
+}
+
+/*-------------------------------Chained Route--------------------------------*/
+
+type chainRoute struct {
+	Route
+	handler Handler
+}
+
+// WrapRoute returns a Route that invokes the given middleware around the
+// Execute of the given route.
+func WrapRoute(rt Route, mw ...Middleware) Route {
+	if len(mw) == 0 {
+		return rt
+	}
+	r := new(chainRoute)
+	r.Route = rt
+	r.handler = Chain(rt.Execute, mw...)
+
+	return r
+}
+
+// Execute invokes the middleware chain, ending with the wrapped route.
+func (r *chainRoute) Execute(ctx Context) {
+	r.handler(ctx)
+}
+
+func (r *chainRoute) prefix() (string, bool) {
+	return routePrefix(r.Route)
+}
+
+func (r *chainRoute) unwrap() Route {
+	return r.Route
+}
+
+// wrapper is implemented by routes that wrap another route.
+type wrapper interface {
+	unwrap() Route
+}
+
+// unwrap returns the innermost route of the given route.
+func unwrap(rt Route) Route {
+	for {
+		w, ok := rt.(wrapper)
+		if !ok {
+			return rt
+		}
+		rt = w.unwrap()
+	}
+}
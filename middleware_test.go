@@ -0,0 +1,122 @@
+// Copyright 2013 Justin Wilson. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package dingo
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+/*-------------------------Helpers-------------------------*/
+func tagMiddleware(tag string) Middleware {
+	return func(next Handler) Handler {
+		return func(ctx Context) {
+			wc(ctx, tag+"(")
+			next(ctx)
+			wc(ctx, ")")
+		}
+	}
+}
+
+func haltMiddleware(next Handler) Handler {
+	return func(ctx Context) {
+		ctx.HttpError(403)
+	}
+}
+
+func serve(s *Server, method, path string) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	r := dummyRequest(path)
+	r.Method = method
+	s.ServeHTTP(w, r)
+	return w
+}
+
+/*-------------------------Tests-------------------------*/
+func TestMiddlewareOrder(t *testing.T) {
+	s := New(nil)
+	s.Use(tagMiddleware("a"), tagMiddleware("b"))
+	s.SRouter("/mw/").Use(tagMiddleware("c")).Use(tagMiddleware("d")).Get(func(ctx Context) { wc(ctx, "h") })
+
+	if w := serve(&s, "GET", "/mw/"); w.Body.String() != "a(b(c(d(h))))" {
+		t.Errorf("Unexpected middleware order: %s", w.Body.String())
+	}
+}
+
+func TestMiddlewareShortCircuit(t *testing.T) {
+	s := New(nil)
+	s.SRouter("/halt/").Use(haltMiddleware).Get(func(ctx Context) { t.Error("Handler invoked after short-circuit") })
+
+	if w := serve(&s, "GET", "/halt/"); w.Code != 403 {
+		t.Errorf("Unexpected status: %d != 403", w.Code)
+	}
+}
+
+func TestGroup(t *testing.T) {
+	s := New(nil)
+	api := s.Group("/api/")
+	api.Use(tagMiddleware("api"))
+	api.SetErrorHandler(func(ctx Context, status int) bool {
+		ctx.Response.WriteHeader(status)
+		wc(ctx, "api error")
+		return true
+	})
+	v2 := api.Group("/v2")
+	v2.Use(tagMiddleware("v2"))
+
+	api.SRoute("/users/", dummyHandler_0, "GET")
+	v2.ReRoute("^/users/(?P<id>\\d+)/$", func(ctx Context) { wc(ctx, ctx.RouteData["id"]) }, "GET")
+	v2.PRouter("/posts/:slug/").Get(func(ctx Context) { wc(ctx, ctx.RouteData["slug"]) })
+	v2.SRoute("/fail/", func(ctx Context) { ctx.HttpError(404) }, "GET")
+
+	paths := map[string]string{
+		"/api/users/":        "api(/api/users/)",
+		"/api/v2/users/42/":  "api(v2(42))",
+		"/api/v2/posts/abc/": "api(v2(abc))",
+		"/api/v2/fail/":      "api(v2(api error))",
+	}
+	for path, body := range paths {
+		if w := serve(&s, "GET", path); w.Body.String() != body {
+			t.Errorf("Unexpected group response for (%s): %s != %s", path, w.Body.String(), body)
+		}
+	}
+}
+
+func TestValues(t *testing.T) {
+	s := New(nil)
+	s.Use(func(next Handler) Handler {
+		return func(ctx Context) {
+			ctx.Set("user", "juztin")
+			next(ctx)
+			wc(ctx, fmt.Sprint(ctx.Get("after")))
+		}
+	})
+	s.SRoute("/values/", func(ctx Context) {
+		wc(ctx, fmt.Sprint(ctx.Get("user")))
+		ctx.Set("after", "!")
+	}, "GET")
+
+	if w := serve(&s, "GET", "/values/"); w.Body.String() != "juztin!" {
+		t.Errorf("Unexpected request values: %s", w.Body.String())
+	}
+}
+
+func TestTimeout(t *testing.T) {
+	s := New(nil)
+	s.SRouter("/slow/").Timeout(time.Millisecond).Get(func(ctx Context) {
+		select {
+		case <-ctx.Done():
+		case <-time.After(time.Second):
+			t.Error("Context wasn't cancelled")
+		}
+	})
+
+	if w := serve(&s, "GET", "/slow/"); w.Code != http.StatusServiceUnavailable {
+		t.Errorf("Unexpected status: %d != 503", w.Code)
+	}
+}
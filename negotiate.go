@@ -0,0 +1,37 @@
+// Copyright 2013 Justin Wilson. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package dingo
+
+import "code.minty.io/dingo/request"
+
+/*--------------------------------Negotiation---------------------------------*/
+
+// ContentType returns the media type of the request body, without any params.
+func (c *Context) ContentType() string {
+	return request.ContentType(c.Request)
+}
+
+// Accepts returns whether the given media type is acceptable to the client.
+func (c *Context) Accepts(mediaType string) bool {
+	return request.Accepts(c.Request, mediaType)
+}
+
+// Negotiate returns the offered media type most preferred by the `Accept`
+// header, or an empty string when none are acceptable.
+func (c *Context) Negotiate(offers ...string) string {
+	return request.Negotiate(c.Request, offers...)
+}
+
+// NegotiateLanguage returns the offered language most preferred by the
+// `Accept-Language` header, or an empty string when none are acceptable.
+func (c *Context) NegotiateLanguage(offers ...string) string {
+	return request.NegotiateLanguage(c.Request, offers...)
+}
+
+// NegotiateEncoding returns the offered encoding most preferred by the
+// `Accept-Encoding` header, or an empty string when none are acceptable.
+func (c *Context) NegotiateEncoding(offers ...string) string {
+	return request.NegotiateEncoding(c.Request, offers...)
+}
@@ -0,0 +1,145 @@
+// Copyright 2013 Justin Wilson. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package dingo
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+var (
+	// paramTypes are the named constraints usable within `{name:type}` params.
+	paramTypes = map[string]string{
+		"int":   `-?[0-9]+`,
+		"uint":  `[0-9]+`,
+		"alpha": `[A-Za-z]+`,
+		"alnum": `[A-Za-z0-9]+`,
+		"hex":   `[0-9A-Fa-f]+`,
+		"slug":  `[A-Za-z0-9_-]+`,
+		"uuid":  `[0-9A-Fa-f]{8}-[0-9A-Fa-f]{4}-[0-9A-Fa-f]{4}-[0-9A-Fa-f]{4}-[0-9A-Fa-f]{12}`,
+	}
+	segment = `[^/]+`
+)
+
+/*----------params----------*/
+
+type pRoute struct {
+	*reRoute
+	pattern string
+}
+
+// NewPRoute returns a new route for the given path/handler, where the path
+// contains named params, eg. `/blog/:year/{slug}/`.
+//
+//	:name         matches a single path segment
+//	{name}        matches a single path segment
+//	{name:type}   matches a `paramTypes` type (int, uint, alpha, alnum, hex, slug, uuid)
+//	{name:regex}  matches the given RegEx
+//	*name         matches the remainder of the path
+//
+// The params are compiled to a RegEx route, with each param available within
+// the `Context.RouteData`. Patterns without a trailing slash also match the
+// path with one, as requests are routed by their canonical path.
+func NewPRoute(pattern string, handler Handler) Route {
+	r := new(pRoute)
+	r.pattern = pattern
+	r.reRoute = NewReRoute(compileParams(pattern), handler).(*reRoute)
+
+	return r
+}
+
+// Returns the path of the route.
+func (r *pRoute) Path() string {
+	return r.pattern
+}
+
+func isParamChar(c byte) bool {
+	return c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9'
+}
+
+func paramName(pattern string, i int) (string, int) {
+	j := i
+	for j < len(pattern) && isParamChar(pattern[j]) {
+		j++
+	}
+	return pattern[i:j], j
+}
+
+// compileParams converts a named param pattern into an anchored RegEx.
+func compileParams(pattern string) string {
+	var (
+		buf   bytes.Buffer
+		names = make(map[string]bool)
+	)
+
+	param := func(name, expr string) {
+		if name == "" {
+			panic(fmt.Sprintf("Param name missing in pattern: %s", pattern))
+		} else if names[name] {
+			panic(fmt.Sprintf("Param `%s` used more than once in pattern: %s", name, pattern))
+		}
+		names[name] = true
+		fmt.Fprintf(&buf, "(?P<%s>%s)", name, expr)
+	}
+
+	buf.WriteByte('^')
+	for i := 0; i < len(pattern); {
+		switch c := pattern[i]; c {
+		case ':', '*':
+			name, j := paramName(pattern, i+1)
+			if c == ':' {
+				param(name, segment)
+			} else {
+				param(name, ".*")
+			}
+			i = j
+		case '{':
+			depth, j := 1, i+1
+			for ; j < len(pattern) && depth > 0; j++ {
+				switch pattern[j] {
+				case '{':
+					depth++
+				case '}':
+					depth--
+				}
+			}
+			if depth > 0 {
+				panic(fmt.Sprintf("Unclosed param in pattern: %s", pattern))
+			}
+
+			name, k := paramName(pattern, i+1)
+			expr := segment
+			if pattern[k] == ':' {
+				expr = pattern[k+1 : j-1]
+				if t, ok := paramTypes[expr]; ok {
+					expr = t
+				} else if _, err := regexp.Compile(expr); err != nil {
+					panic(fmt.Sprintf("Invalid param `%s` in pattern: %s, %s", name, pattern, err))
+				}
+			} else if k != j-1 {
+				panic(fmt.Sprintf("Invalid param name in pattern: %s", pattern))
+			}
+			param(name, expr)
+			i = j
+		default:
+			// copy the literal run as-is, keeping multi-byte characters intact
+			j := i + 1
+			for j < len(pattern) && !strings.ContainsRune(":*{", rune(pattern[j])) {
+				j++
+			}
+			buf.WriteString(regexp.QuoteMeta(pattern[i:j]))
+			i = j
+		}
+	}
+	if !strings.HasSuffix(pattern, "/") {
+		// requests are routed by their canonical path, which ends with a slash
+		buf.WriteString("/?")
+	}
+	buf.WriteByte('$')
+
+	return buf.String()
+}
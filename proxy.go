@@ -0,0 +1,275 @@
+// Copyright 2013 Justin Wilson. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// +build !appengine
+
+package dingo
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+var (
+	proxyV1Sig = []byte("PROXY ")
+	proxyV2Sig = []byte("\r\n\r\n\x00\r\nQUIT\n")
+
+	// proxyV1Max is the maximum length of a v1 header, including the CRLF.
+	proxyV1Max = 107
+)
+
+// ProxyOptions configures the listener returned by `ProxyListener`.
+type ProxyOptions struct {
+	// Trusted are the CIDRs, or IPs, of the balancers permitted to send
+	// PROXY headers. Headers are only parsed for connections from trusted
+	// sources, when empty no sources are trusted.
+	Trusted []string
+	// Timeout is how long to wait for the header, defaulting to 5 seconds.
+	Timeout time.Duration
+}
+
+/*-------------------------------Proxy Listener-------------------------------*/
+
+type proxyListener struct {
+	net.Listener
+	trusted trustedNets
+	timeout time.Duration
+}
+
+// ProxyListener returns the given listener, parsing PROXY protocol v1 and v2
+// headers sent by load balancers, so the connection's RemoteAddr is that of the
+// client. Connections without a header are served as-is.
+//
+// The header is parsed on the first Read, or RemoteAddr, of the connection, so
+// a slow client doesn't block Accept. TLS listeners are wrapped beneath the TLS
+// handshake, as balancers send the header before it.
+func ProxyListener(l net.Listener, opts ProxyOptions) (net.Listener, error) {
+	trusted, err := parseTrusted(opts.Trusted)
+	if err != nil {
+		return nil, err
+	}
+	if t, ok := l.(*tlsListener); ok {
+		c := *t
+		if c.Listener, err = ProxyListener(t.Listener, opts); err != nil {
+			return nil, err
+		}
+		return &c, nil
+	}
+
+	pl := &proxyListener{Listener: l, trusted: trusted, timeout: opts.Timeout}
+	if pl.timeout <= 0 {
+		pl.timeout = 5 * time.Second
+	}
+	return pl, nil
+}
+
+// Accept waits for, and returns, the next connection.
+func (l *proxyListener) Accept() (net.Conn, error) {
+	c, err := l.Listener.Accept()
+	if err != nil {
+		return nil, err
+	}
+	if !l.trusted.contains(remoteIP(c)) {
+		return c, nil
+	}
+	return &proxyConn{Conn: c, r: bufio.NewReader(c), timeout: l.timeout}, nil
+}
+
+func (l *proxyListener) unwrapListener() net.Listener {
+	return l.Listener
+}
+
+/*--------------------------------Proxy Conn----------------------------------*/
+
+type proxyConn struct {
+	net.Conn
+	r       *bufio.Reader
+	timeout time.Duration
+	once    sync.Once
+	err     error
+	remote  net.Addr
+	local   net.Addr
+
+	mu       sync.Mutex
+	deadline time.Time
+}
+
+// init parses the header, once, restoring the read deadline set by the server.
+func (c *proxyConn) init() {
+	c.once.Do(func() {
+		c.Conn.SetReadDeadline(time.Now().Add(c.timeout))
+		c.remote, c.local, c.err = readProxyHeader(c.r)
+		c.mu.Lock()
+		c.Conn.SetReadDeadline(c.deadline)
+		c.mu.Unlock()
+	})
+}
+
+// Read reads from the connection, following the header.
+func (c *proxyConn) Read(b []byte) (int, error) {
+	if c.init(); c.err != nil {
+		return 0, c.err
+	}
+	return c.r.Read(b)
+}
+
+// RemoteAddr returns the client address given by the header, or the address
+// of the peer without one.
+func (c *proxyConn) RemoteAddr() net.Addr {
+	if c.init(); c.remote != nil {
+		return c.remote
+	}
+	return c.Conn.RemoteAddr()
+}
+
+// LocalAddr returns the address the client connected to, as given by the
+// header, or the local address without one.
+func (c *proxyConn) LocalAddr() net.Addr {
+	if c.init(); c.local != nil {
+		return c.local
+	}
+	return c.Conn.LocalAddr()
+}
+
+// SetDeadline sets the read and write deadlines of the connection.
+func (c *proxyConn) SetDeadline(t time.Time) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.deadline = t
+	return c.Conn.SetDeadline(t)
+}
+
+// SetReadDeadline sets the read deadline of the connection.
+func (c *proxyConn) SetReadDeadline(t time.Time) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.deadline = t
+	return c.Conn.SetReadDeadline(t)
+}
+
+/*-----------------------------------Parse------------------------------------*/
+
+// readProxyHeader reads a v1 or v2 header, returning the source and
+// destination addresses, which are nil when not given.
+func readProxyHeader(r *bufio.Reader) (net.Addr, net.Addr, error) {
+	for n := 1; ; n++ {
+		b, err := r.Peek(n)
+		if err != nil {
+			if err == io.EOF {
+				err = nil
+			}
+			return nil, nil, err
+		}
+		v1, v2 := bytes.HasPrefix(proxyV1Sig, b), bytes.HasPrefix(proxyV2Sig, b)
+		switch {
+		case v1 && n == len(proxyV1Sig):
+			return readProxyV1(r)
+		case v2 && n == len(proxyV2Sig):
+			return readProxyV2(r)
+		case !v1 && !v2:
+			return nil, nil, nil
+		}
+	}
+}
+
+func readProxyV1(r *bufio.Reader) (net.Addr, net.Addr, error) {
+	var line []byte
+	for len(line) < proxyV1Max {
+		b, err := r.ReadByte()
+		if err != nil {
+			return nil, nil, err
+		}
+		if line = append(line, b); b == '\n' {
+			break
+		}
+	}
+	if !bytes.HasSuffix(line, []byte("\r\n")) {
+		return nil, nil, errors.New("dingo: invalid PROXY v1 header")
+	}
+
+	fields := strings.Split(string(line[:len(line)-2]), " ")
+	if len(fields) >= 2 && fields[1] == "UNKNOWN" {
+		return nil, nil, nil
+	}
+	if len(fields) != 6 || fields[1] != "TCP4" && fields[1] != "TCP6" {
+		return nil, nil, fmt.Errorf("dingo: invalid PROXY v1 header %q", line)
+	}
+	src, err := proxyV1Addr(fields[2], fields[4])
+	if err != nil {
+		return nil, nil, err
+	}
+	dst, err := proxyV1Addr(fields[3], fields[5])
+	if err != nil {
+		return nil, nil, err
+	}
+	return src, dst, nil
+}
+
+func proxyV1Addr(ip, port string) (net.Addr, error) {
+	addr := &net.TCPAddr{IP: net.ParseIP(ip)}
+	p, err := strconv.ParseUint(port, 10, 16)
+	if addr.IP == nil || err != nil {
+		return nil, fmt.Errorf("dingo: invalid PROXY v1 address %s:%s", ip, port)
+	}
+	addr.Port = int(p)
+	return addr, nil
+}
+
+func readProxyV2(r *bufio.Reader) (net.Addr, net.Addr, error) {
+	var head [16]byte
+	if _, err := io.ReadFull(r, head[:]); err != nil {
+		return nil, nil, err
+	}
+	if head[12]>>4 != 2 {
+		return nil, nil, fmt.Errorf("dingo: invalid PROXY v2 version %d", head[12]>>4)
+	}
+	body := make([]byte, binary.BigEndian.Uint16(head[14:]))
+	if _, err := io.ReadFull(r, body); err != nil {
+		return nil, nil, err
+	}
+
+	// LOCAL connections, such as health checks, are from the balancer itself
+	if head[12]&0xF == 0 {
+		return nil, nil, nil
+	}
+	if head[12]&0xF != 1 {
+		return nil, nil, fmt.Errorf("dingo: invalid PROXY v2 command %d", head[12]&0xF)
+	}
+
+	switch head[13] >> 4 {
+	case 1, 2: // inet, inet6
+		size := net.IPv4len
+		if head[13]>>4 == 2 {
+			size = net.IPv6len
+		}
+		if len(body) < 2*size+4 {
+			return nil, nil, errors.New("dingo: short PROXY v2 address")
+		}
+		ports := body[2*size:]
+		src := &net.TCPAddr{IP: net.IP(body[:size]), Port: int(binary.BigEndian.Uint16(ports))}
+		dst := &net.TCPAddr{IP: net.IP(body[size : 2*size]), Port: int(binary.BigEndian.Uint16(ports[2:]))}
+		return src, dst, nil
+	case 3: // unix
+		if len(body) < 216 {
+			return nil, nil, errors.New("dingo: short PROXY v2 address")
+		}
+		name := func(b []byte) string {
+			if i := bytes.IndexByte(b, 0); i >= 0 {
+				b = b[:i]
+			}
+			return string(b)
+		}
+		return &net.UnixAddr{Name: name(body[:108]), Net: "unix"}, &net.UnixAddr{Name: name(body[108:216]), Net: "unix"}, nil
+	}
+	return nil, nil, nil
+}
@@ -0,0 +1,113 @@
+// Copyright 2013 Justin Wilson. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// +build !appengine
+
+package dingo
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/binary"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"testing"
+)
+
+/*-------------------------Helpers-------------------------*/
+func proxyV2(src, dst *net.TCPAddr) []byte {
+	var b bytes.Buffer
+	b.Write(proxyV2Sig)
+	b.Write([]byte{0x21, 0x11, 0, 12})
+	b.Write(src.IP.To4())
+	b.Write(dst.IP.To4())
+	binary.Write(&b, binary.BigEndian, uint16(src.Port))
+	binary.Write(&b, binary.BigEndian, uint16(dst.Port))
+	return b.Bytes()
+}
+
+func proxyServer(t *testing.T, opts ProxyOptions) (Server, net.Listener) {
+	l, err := HTTPListener("127.0.0.1", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pl, err := ProxyListener(l, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := New(pl)
+	s.SRoute("/addr/", func(ctx Context) { wc(ctx, ctx.RemoteAddr) }, "GET")
+	go s.Serve()
+	return s, l
+}
+
+func proxyGet(t *testing.T, l net.Listener, header []byte) (int, string) {
+	c, err := net.Dial("tcp", l.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	c.Write(header)
+	c.Write([]byte("GET /addr/ HTTP/1.1\r\nHost: localhost\r\nConnection: close\r\n\r\n"))
+	res, err := http.ReadResponse(bufio.NewReader(c), nil)
+	if err != nil {
+		return 0, err.Error()
+	}
+	defer res.Body.Close()
+	b, _ := ioutil.ReadAll(res.Body)
+	return res.StatusCode, string(b)
+}
+
+/*-------------------------Tests-------------------------*/
+func TestProxyListener(t *testing.T) {
+	s, l := proxyServer(t, ProxyOptions{Trusted: []string{"127.0.0.1"}})
+	defer s.Shutdown(context.Background())
+
+	src := &net.TCPAddr{IP: net.ParseIP("203.0.113.7"), Port: 4321}
+	dst := &net.TCPAddr{IP: net.ParseIP("198.51.100.1"), Port: 443}
+	headers := map[string][]byte{
+		"v1":      []byte("PROXY TCP4 203.0.113.7 198.51.100.1 4321 443\r\n"),
+		"v1 IPv6": []byte("PROXY TCP6 2001:db8::7 2001:db8::1 4321 443\r\n"),
+		"v2":      proxyV2(src, dst),
+	}
+	expected := map[string]string{
+		"v1":      "203.0.113.7:4321",
+		"v1 IPv6": "[2001:db8::7]:4321",
+		"v2":      "203.0.113.7:4321",
+	}
+	for name, h := range headers {
+		if code, body := proxyGet(t, l, h); code != 200 || body != expected[name] {
+			t.Errorf("Unexpected %s response: %d, %s != %s", name, code, body, expected[name])
+		}
+	}
+
+	if code, body := proxyGet(t, l, nil); code != 200 || body[:10] != "127.0.0.1:" {
+		t.Errorf("Unexpected response without a header: %d, %s", code, body)
+	}
+	if code, _ := proxyGet(t, l, []byte("PROXY TCP4 nope\r\n")); code == 200 {
+		t.Error("Invalid header was served")
+	}
+}
+
+func TestProxyListenerUntrusted(t *testing.T) {
+	for _, trusted := range [][]string{{"10.0.0.0/8"}, nil} {
+		s, l := proxyServer(t, ProxyOptions{Trusted: trusted})
+		if code, body := proxyGet(t, l, []byte("PROXY TCP4 203.0.113.7 198.51.100.1 4321 443\r\n")); code == 200 {
+			t.Errorf("Header from an untrusted source was parsed, trusting %v: %s", trusted, body)
+		}
+		s.Shutdown(context.Background())
+	}
+
+	l, err := HTTPListener("127.0.0.1", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	if _, err := ProxyListener(l, ProxyOptions{Trusted: []string{"nope"}}); err == nil {
+		t.Error("Invalid trusted CIDR didn't fail")
+	}
+}
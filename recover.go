@@ -0,0 +1,101 @@
+// Copyright 2013 Justin Wilson. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package dingo
+
+import (
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"log"
+	"net/http"
+	"runtime/debug"
+	"time"
+)
+
+/*----------------------------------Panic-------------------------------------*/
+
+// Panic describes a panic recovered while serving a request.
+type Panic struct {
+	Value  interface{}
+	Stack  []byte
+	Method string
+	Path   string
+	Route  string
+	Time   time.Time
+}
+
+// Reporter receives the panics recovered by a Server.
+type Reporter interface {
+	Report(p Panic)
+}
+
+// ReporterFunc is a func type implementing Reporter.
+type ReporterFunc func(p Panic)
+
+// Report invokes the func with the given panic.
+func (fn ReporterFunc) Report(p Panic) {
+	fn(p)
+}
+
+// LogReporter reports panics, along with their stack, to the standard logger.
+var LogReporter Reporter = ReporterFunc(func(p Panic) {
+	log.Printf("dingo: panic serving %s %s (%s): %v\n%s", p.Method, p.Path, p.Route, p.Value, p.Stack)
+})
+
+var debugTmpl = template.Must(template.New("_dingopanic_").Parse(`<!doctype html>
+<html><head><title>500 Internal Server Error</title></head>
+<body><h1>panic: {{.Value}}</h1><p>{{.Method}} {{.Path}} ({{.Route}})</p><pre>{{printf "%s" .Stack}}</pre></body></html>`))
+
+// recovered reports the panic and, when the response hasn't been started,
+// writes the 500 response.
+func (s *Server) recovered(ctx Context, res ResponseWriter, rt Route, v interface{}) {
+	p := Panic{
+		Value:  v,
+		Stack:  debug.Stack(),
+		Method: ctx.Method,
+		Path:   ctx.URL.Path,
+		Time:   time.Now(),
+	}
+	if rt != nil {
+		p.Route = rt.Path()
+	}
+
+	reporter := s.Reporter
+	if reporter == nil {
+		reporter = LogReporter
+	}
+	reporter.Report(p)
+
+	if res.Written() {
+		// the client has already received a status, it can't be changed
+		return
+	}
+	ctx.Response = res
+	if !s.Debug {
+		err, ok := v.(error)
+		if !ok {
+			err = fmt.Errorf("%v", v)
+		}
+		ctx.Fail(http.StatusInternalServerError, err)
+		return
+	}
+
+	h := res.Header()
+	if ctx.Negotiate("text/html", "application/json") == "application/json" {
+		h.Set("Content-Type", "application/json; charset=utf-8")
+		res.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(res).Encode(map[string]string{
+			"error":  fmt.Sprint(p.Value),
+			"method": p.Method,
+			"path":   p.Path,
+			"route":  p.Route,
+			"stack":  string(p.Stack),
+		})
+	} else {
+		h.Set("Content-Type", "text/html; charset=utf-8")
+		res.WriteHeader(http.StatusInternalServerError)
+		debugTmpl.Execute(res, p)
+	}
+}
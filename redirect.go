@@ -0,0 +1,70 @@
+// Copyright 2013 Justin Wilson. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package dingo
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// NewRedirectTLS returns a new Server, for the given listener, that redirects
+// every request to HTTPS on the given host/port. When host is empty the host of
+// the request is used, and port 443 is omitted from the redirect. Requests
+// without a host, such as HTTP/1.0 requests, are answered with a 400.
+func NewRedirectTLS(l net.Listener, host string, port int) Server {
+	s := New(l)
+	s.fallback = func(ctx Context) {
+		h := host
+		if h == "" {
+			h = ctx.Request.Host
+			if hh, _, err := net.SplitHostPort(h); err == nil {
+				h = hh
+			}
+			if h == "" {
+				ctx.HttpError(http.StatusBadRequest)
+				return
+			}
+		}
+		// IPv6 hosts are bracketed only when joined, or without a port
+		h = strings.TrimSuffix(strings.TrimPrefix(h, "["), "]")
+		if port != 443 {
+			h = net.JoinHostPort(h, strconv.Itoa(port))
+		} else if strings.Contains(h, ":") {
+			h = "[" + h + "]"
+		}
+
+		u := *ctx.URL
+		u.Scheme, u.Host = "https", h
+		status := http.StatusMovedPermanently
+		if ctx.Method != "GET" && ctx.Method != "HEAD" {
+			// keep the method, and body, of the request
+			status = http.StatusPermanentRedirect
+		}
+		http.Redirect(ctx.Response, ctx.Request, u.String(), status)
+	}
+	return s
+}
+
+// StrictTransportSecurity has the server emit a `Strict-Transport-Security`
+// header, on TLS responses, with the given max-age and directives.
+// A max-age of zero sends `max-age=0`, having browsers drop the HSTS policy,
+// while a negative max-age removes the header.
+func (s *Server) StrictTransportSecurity(maxAge time.Duration, subdomains, preload bool) {
+	if maxAge < 0 {
+		s.hsts = ""
+		return
+	}
+	s.hsts = fmt.Sprint("max-age=", int64(maxAge/time.Second))
+	if subdomains {
+		s.hsts += "; includeSubDomains"
+	}
+	if preload {
+		s.hsts += "; preload"
+	}
+}
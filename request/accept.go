@@ -0,0 +1,205 @@
+// Copyright 2013 Justin Wilson. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package request
+
+import (
+	"mime"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Accept is a single value of an `Accept`, `Accept-Language` or `Accept-Encoding` header.
+type Accept struct {
+	Value  string
+	Q      float64
+	Params map[string]string
+}
+
+type byQ []Accept
+
+func (a byQ) Len() int           { return len(a) }
+func (a byQ) Less(i, j int) bool { return a[i].Q > a[j].Q }
+func (a byQ) Swap(i, j int)      { a[i], a[j] = a[j], a[i] }
+
+// ParseAccept parses an `Accept` style header, returning it's values ordered
+// by quality (q-value), highest first. Values of equal quality keep their order.
+func ParseAccept(header string) []Accept {
+	var accepts []Accept
+	for _, part := range strings.Split(header, ",") {
+		fields := strings.Split(part, ";")
+		a := Accept{Value: strings.ToLower(strings.TrimSpace(fields[0])), Q: 1}
+		if a.Value == "" {
+			continue
+		}
+		for _, f := range fields[1:] {
+			kv := strings.SplitN(f, "=", 2)
+			k := strings.ToLower(strings.TrimSpace(kv[0]))
+			v := ""
+			if len(kv) == 2 {
+				v = strings.Trim(strings.TrimSpace(kv[1]), `"`)
+			}
+			if k == "q" {
+				if q, err := strconv.ParseFloat(v, 64); err == nil && q >= 0 && q <= 1 {
+					a.Q = q
+				}
+				continue
+			}
+			if a.Params == nil {
+				a.Params = make(map[string]string)
+			}
+			a.Params[k] = v
+		}
+		accepts = append(accepts, a)
+	}
+	sort.Stable(byQ(accepts))
+	return accepts
+}
+
+// matcher returns the specificity of the range matching the offer, or zero
+// when the range doesn't match.
+type matcher func(rng, offer string) int
+
+func matchMedia(rng, offer string) int {
+	switch {
+	case rng == offer:
+		return 3
+	case rng == "*/*":
+		return 1
+	case strings.HasSuffix(rng, "/*") && strings.HasPrefix(offer, rng[:len(rng)-1]):
+		return 2
+	}
+	return 0
+}
+
+func matchLanguage(rng, offer string) int {
+	switch {
+	case rng == offer:
+		return 3
+	case rng == "*":
+		return 1
+	case strings.HasPrefix(offer, rng+"-"):
+		return 2
+	}
+	return 0
+}
+
+func matchEncoding(rng, offer string) int {
+	switch {
+	case rng == offer:
+		return 2
+	case rng == "*":
+		return 1
+	}
+	return 0
+}
+
+// quality returns the q-value, and specificity, of the most specific range matching the offer.
+func quality(accepts []Accept, offer string, match matcher) (float64, int) {
+	q, best := 0.0, 0
+	for _, a := range accepts {
+		if s := match(a.Value, offer); s > best {
+			q, best = a.Q, s
+		}
+	}
+	return q, best
+}
+
+// negotiate returns the offer with the highest quality, preferring the more
+// specific match, then the earlier offer, when equal.
+func negotiate(accepts []Accept, offers []string, match matcher, fallback func(offer string) float64) string {
+	best, bestQ, bestS := "", 0.0, 0
+	for _, offer := range offers {
+		q, s := quality(accepts, strings.ToLower(offer), match)
+		if s == 0 {
+			q = fallback(offer)
+		}
+		if q > bestQ || q == bestQ && q > 0 && s > bestS {
+			best, bestQ, bestS = offer, q, s
+		}
+	}
+	return best
+}
+
+func none(offer string) float64 {
+	return 0
+}
+
+/*---------------------------------Content-Type-------------------------------*/
+
+// ContentType returns the media type of the request body, without any params.
+func ContentType(r *http.Request) string {
+	ct, _, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if err != nil {
+		return ""
+	}
+	return ct
+}
+
+// IsContentType returns whether the request body is of the given media type.
+func IsContentType(r *http.Request, mediaType string) bool {
+	return ContentType(r) == strings.ToLower(mediaType)
+}
+
+/*-----------------------------------Accept-----------------------------------*/
+
+// Accepts returns whether the given media type is acceptable to the client.
+// Any media type is acceptable when the request has no `Accept` header.
+func Accepts(r *http.Request, mediaType string) bool {
+	return Negotiate(r, mediaType) != ""
+}
+
+// Negotiate returns the offered media type most preferred by the `Accept`
+// header of the request, or an empty string when none are acceptable.
+// The first offer is returned when the request has no `Accept` header.
+func Negotiate(r *http.Request, offers ...string) string {
+	h := r.Header.Get("Accept")
+	if h == "" {
+		if len(offers) == 0 {
+			return ""
+		}
+		return offers[0]
+	}
+	return negotiate(ParseAccept(h), offers, matchMedia, none)
+}
+
+// AcceptLanguage returns the languages of the `Accept-Language` header, most preferred first.
+func AcceptLanguage(r *http.Request) []Accept {
+	return ParseAccept(r.Header.Get("Accept-Language"))
+}
+
+// NegotiateLanguage returns the offered language most preferred by the
+// `Accept-Language` header of the request, or an empty string when none are acceptable.
+// The first offer is returned when the request has no `Accept-Language` header.
+func NegotiateLanguage(r *http.Request, offers ...string) string {
+	h := r.Header.Get("Accept-Language")
+	if h == "" {
+		if len(offers) == 0 {
+			return ""
+		}
+		return offers[0]
+	}
+	return negotiate(ParseAccept(h), offers, matchLanguage, none)
+}
+
+// AcceptEncoding returns the encodings of the `Accept-Encoding` header, most preferred first.
+func AcceptEncoding(r *http.Request) []Accept {
+	return ParseAccept(r.Header.Get("Accept-Encoding"))
+}
+
+// NegotiateEncoding returns the offered encoding most preferred by the
+// `Accept-Encoding` header of the request, or an empty string when none are
+// acceptable. The "identity" encoding is acceptable unless explicitly refused.
+func NegotiateEncoding(r *http.Request, offers ...string) string {
+	identity := func(offer string) float64 {
+		if strings.ToLower(offer) == "identity" {
+			// less preferred than any other acceptable encoding
+			return 0.001
+		}
+		return 0
+	}
+	return negotiate(AcceptEncoding(r), offers, matchEncoding, identity)
+}
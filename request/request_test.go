@@ -0,0 +1,89 @@
+// Copyright 2013 Justin Wilson. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package request
+
+import (
+	"net/http"
+	"testing"
+)
+
+func headerRequest(key, value string) *http.Request {
+	r := &http.Request{Header: make(http.Header)}
+	if value != "" {
+		r.Header.Set(key, value)
+	}
+	return r
+}
+
+type negotiateTest struct {
+	header, expected string
+	offers           []string
+}
+
+var negotiateCol = []negotiateTest{
+	{"", "application/json", []string{"application/json", "text/html"}},
+	{"text/html", "text/html", []string{"application/json", "text/html"}},
+	{"text/*;q=0.5, application/json", "application/json", []string{"text/html", "application/json"}},
+	{"text/html;q=0.1, */*;q=0.8", "application/json", []string{"text/html", "application/json"}},
+	{"text/html;level=1, text/*;q=0", "", []string{"text/plain"}},
+	{"application/xml", "", []string{"application/json"}},
+	{"TEXT/HTML", "text/html", []string{"text/html"}},
+}
+
+var languageCol = []negotiateTest{
+	{"", "en", []string{"en", "fr"}},
+	{"fr-CA, fr;q=0.8, en;q=0.5", "fr", []string{"en", "fr"}},
+	{"en-us;q=0.5, de", "de", []string{"en-US", "de-DE", "de"}},
+	{"da, *;q=0.1", "en", []string{"en"}},
+}
+
+var encodingCol = []negotiateTest{
+	{"", "identity", []string{"gzip", "identity"}},
+	{"gzip, deflate", "gzip", []string{"identity", "gzip"}},
+	{"deflate;q=0.5, gzip;q=1.0", "gzip", []string{"deflate", "gzip"}},
+	{"gzip;q=0, identity", "identity", []string{"gzip", "identity"}},
+	{"*;q=0", "", []string{"gzip", "identity"}},
+}
+
+func TestNegotiate(t *testing.T) {
+	for _, n := range negotiateCol {
+		if m := Negotiate(headerRequest("Accept", n.header), n.offers...); m != n.expected {
+			t.Errorf("Unexpected media type for (%s): %s != %s", n.header, m, n.expected)
+		}
+	}
+}
+
+func TestNegotiateLanguage(t *testing.T) {
+	for _, n := range languageCol {
+		if m := NegotiateLanguage(headerRequest("Accept-Language", n.header), n.offers...); m != n.expected {
+			t.Errorf("Unexpected language for (%s): %s != %s", n.header, m, n.expected)
+		}
+	}
+}
+
+func TestNegotiateEncoding(t *testing.T) {
+	for _, n := range encodingCol {
+		if m := NegotiateEncoding(headerRequest("Accept-Encoding", n.header), n.offers...); m != n.expected {
+			t.Errorf("Unexpected encoding for (%s): %s != %s", n.header, m, n.expected)
+		}
+	}
+}
+
+func TestParseAccept(t *testing.T) {
+	a := ParseAccept(`text/html;level=1;q=0.5, application/json, text/plain;q=0.5`)
+	if len(a) != 3 || a[0].Value != "application/json" || a[1].Value != "text/html" || a[2].Value != "text/plain" {
+		t.Errorf("Unexpected accept order: %v", a)
+	} else if a[1].Q != 0.5 || a[1].Params["level"] != "1" {
+		t.Errorf("Unexpected accept params: %v", a[1])
+	}
+}
+
+func TestContentType(t *testing.T) {
+	r := headerRequest("Content-Type", "application/json; charset=utf-8")
+	r.Header.Set("Accept", "text/html")
+	if !IsContentType(r, "application/json") || IsContentType(r, "text/html") {
+		t.Errorf("Unexpected content type: %s", ContentType(r))
+	}
+}
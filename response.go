@@ -0,0 +1,153 @@
+// Copyright 2013 Justin Wilson. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package dingo
+
+import (
+	"bufio"
+	"net"
+	"net/http"
+	"strconv"
+)
+
+/*-------------------------------ResponseWriter-------------------------------*/
+
+// ResponseWriter is the `http.ResponseWriter` given to handlers as the
+// `Context.Response`, recording the status and size of the response.
+type ResponseWriter interface {
+	http.ResponseWriter
+	http.Flusher
+	http.Hijacker
+	http.Pusher
+
+	// Status returns the status written, or zero if the header hasn't been written.
+	Status() int
+	// Size returns the number of bytes of the body written.
+	Size() int
+	// Written returns whether the header has been written.
+	Written() bool
+}
+
+type response struct {
+	http.ResponseWriter
+	status int
+	size   int
+}
+
+// NewResponseWriter returns a ResponseWriter wrapping the given writer.
+func NewResponseWriter(w http.ResponseWriter) ResponseWriter {
+	if rw, ok := w.(ResponseWriter); ok {
+		return rw
+	}
+	return &response{ResponseWriter: w}
+}
+
+// WriteHeader writes the header with the given status.
+// Only the first call is written, any others are ignored.
+func (w *response) WriteHeader(status int) {
+	if w.status != 0 {
+		return
+	}
+	w.status = status
+	w.ResponseWriter.WriteHeader(status)
+}
+
+// Write writes the data to the response, writing the header first if needed.
+func (w *response) Write(data []byte) (int, error) {
+	w.WriteHeader(http.StatusOK)
+	n, err := w.ResponseWriter.Write(data)
+	w.size += n
+	return n, err
+}
+
+// Flush sends any buffered data to the client.
+func (w *response) Flush() {
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		w.WriteHeader(http.StatusOK)
+		f.Flush()
+	}
+}
+
+// Hijack lets the caller take over the connection.
+func (w *response) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	if h, ok := w.ResponseWriter.(http.Hijacker); ok {
+		return h.Hijack()
+	}
+	return nil, nil, http.ErrNotSupported
+}
+
+// Push initiates an HTTP/2 server push.
+func (w *response) Push(target string, opts *http.PushOptions) error {
+	if p, ok := w.ResponseWriter.(http.Pusher); ok {
+		return p.Push(target, opts)
+	}
+	return http.ErrNotSupported
+}
+
+// Status returns the status written, or zero if the header hasn't been written.
+func (w *response) Status() int {
+	return w.status
+}
+
+// Size returns the number of bytes of the body written.
+func (w *response) Size() int {
+	return w.size
+}
+
+// Written returns whether the header has been written.
+func (w *response) Written() bool {
+	return w.status != 0
+}
+
+/*------------------------------Head Response---------------------------------*/
+
+// headResponse answers a HEAD request using a GET route, discarding the body
+// and holding the header until the route has finished so the Content-Length
+// of the discarded body can be set.
+type headResponse struct {
+	ResponseWriter
+	status int
+	size   int
+}
+
+// WriteHeader records the status to be written when the route has finished.
+func (w *headResponse) WriteHeader(status int) {
+	if w.status == 0 {
+		w.status = status
+	}
+}
+
+// Write discards the data, recording it's length.
+func (w *headResponse) Write(data []byte) (int, error) {
+	w.WriteHeader(http.StatusOK)
+	w.size += len(data)
+	return len(data), nil
+}
+
+// Flush does nothing, as the header is held until the route has finished.
+func (w *headResponse) Flush() {}
+
+// Status returns the status to be written.
+func (w *headResponse) Status() int {
+	return w.status
+}
+
+// Size returns the number of bytes of the body discarded.
+func (w *headResponse) Size() int {
+	return w.size
+}
+
+// Written returns whether the route has written the header.
+func (w *headResponse) Written() bool {
+	return w.status != 0
+}
+
+func (w *headResponse) flush() {
+	w.WriteHeader(http.StatusOK)
+	h := w.Header()
+	if h.Get("Content-Length") == "" && h.Get("Transfer-Encoding") == "" {
+		h.Set("Content-Length", strconv.Itoa(w.size))
+	}
+	w.ResponseWriter.WriteHeader(w.status)
+}
@@ -3,12 +3,14 @@ package rest
 import (
 	"encoding/json"
 	"encoding/xml"
+	"errors"
 	"fmt"
 	"io/ioutil"
 	"mime"
 	"net/http"
 
 	"code.minty.io/dingo"
+	"code.minty.io/dingo/validate"
 )
 
 type Handler func(ctx dingo.Context) (int, interface{})
@@ -53,6 +55,9 @@ func JSONHandler(fn Handler, ctx dingo.Context) {
 
 	ctx.Response.Header().Set("Content-Type", ct)
 	status, o := fn(ctx)
+	if errs, ok := validationErrors(o); ok {
+		status, o = http.StatusUnprocessableEntity, errs
+	}
 	ctx.Response.WriteHeader(status)
 	if o == nil && len(callback) < 1 {
 		return
@@ -70,6 +75,24 @@ func JSONHandler(fn Handler, ctx dingo.Context) {
 	ctx.Response.Write(data)
 }
 
+// validationErrors returns the validate.Errors of o, which may be a pointer to,
+// or an error wrapping, them.
+func validationErrors(o interface{}) (validate.Errors, bool) {
+	err, ok := o.(error)
+	if !ok {
+		return nil, false
+	}
+	var errs validate.Errors
+	if errors.As(err, &errs) {
+		return errs, true
+	}
+	var perrs *validate.Errors
+	if errors.As(err, &perrs) && perrs != nil {
+		return *perrs, true
+	}
+	return nil, false
+}
+
 func Wrap(fn Handler) dingo.Handler {
 	return func(ctx dingo.Context) {
 		JSONHandler(fn, ctx)
@@ -0,0 +1,88 @@
+// Copyright 2013 Justin Wilson. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package rest
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"code.minty.io/dingo"
+	"code.minty.io/dingo/validate"
+)
+
+type post struct {
+	Title string `json:"title" validate:"required"`
+	Code  string `json:"code" validate:"len=3"`
+}
+
+func TestValidationErrors(t *testing.T) {
+	verr := func() error { return validate.Struct(post{Code: "ab"}) }
+	results := map[string]func() interface{}{
+		"value":   func() interface{} { return verr() },
+		"pointer": func() interface{} { errs := verr().(validate.Errors); return &errs },
+		"wrapped": func() interface{} { return fmt.Errorf("saving post, %w", verr()) },
+	}
+	expected := []validate.FieldError{
+		{Field: "title", Rule: "required", Message: "is required"},
+		{Field: "code", Rule: "len", Param: "3", Message: "must have a length of 3"},
+	}
+
+	decoders := map[string]func(b []byte) ([]validate.FieldError, error){
+		"application/json": func(b []byte) ([]validate.FieldError, error) {
+			var errs []validate.FieldError
+			return errs, json.Unmarshal(b, &errs)
+		},
+		"application/xml": func(b []byte) ([]validate.FieldError, error) {
+			var doc struct {
+				XMLName xml.Name              `xml:"errors"`
+				Errors  []validate.FieldError `xml:"error"`
+			}
+			return doc.Errors, xml.Unmarshal(b, &doc)
+		},
+	}
+
+	for ct, decode := range decoders {
+		for name, result := range results {
+			w := httptest.NewRecorder()
+			r := httptest.NewRequest("POST", "/posts/", nil)
+			r.Header.Set("Accept", ct)
+			Wrap(func(ctx dingo.Context) (int, interface{}) { return http.StatusOK, result() })(dingo.NewContext(w, r))
+
+			if w.Code != http.StatusUnprocessableEntity {
+				t.Errorf("Unexpected status for %s %s errors: %d != 422", ct, name, w.Code)
+			}
+			errs, err := decode(w.Body.Bytes())
+			if err != nil {
+				t.Errorf("Unexpected body for %s %s errors: %s, %s", ct, name, w.Body.String(), err)
+				continue
+			}
+			if len(errs) != len(expected) {
+				t.Errorf("Unexpected field errors for %s %s errors: %+v", ct, name, errs)
+				continue
+			}
+			for i, e := range errs {
+				if e != expected[i] {
+					t.Errorf("Unexpected field error for %s %s errors: %+v != %+v", ct, name, e, expected[i])
+				}
+			}
+		}
+	}
+}
+
+func TestStatus(t *testing.T) {
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest("GET", "/posts/1/", nil)
+	Wrap(func(ctx dingo.Context) (int, interface{}) {
+		return http.StatusCreated, map[string]string{"title": "Hello"}
+	})(dingo.NewContext(w, r))
+
+	if w.Code != http.StatusCreated || w.Body.String() != `{"title":"Hello"}` {
+		t.Errorf("Unexpected response: %d, %s", w.Code, w.Body.String())
+	}
+}
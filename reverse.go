@@ -0,0 +1,121 @@
+// Copyright 2013 Justin Wilson. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package dingo
+
+import (
+	"bytes"
+	"fmt"
+	"net/url"
+	"regexp"
+	"regexp/syntax"
+)
+
+// expressioner is implemented by routes that can report the RegEx their path is matched by.
+type expressioner interface {
+	expression() string
+}
+
+func (r *route) expression() string {
+	return "^" + regexp.QuoteMeta(r.path) + "$"
+}
+
+func (r *reRoute) expression() string {
+	return r.expr.String()
+}
+
+func (r *rRoute) expression() string {
+	return r.expr.String()
+}
+
+/*---------------------------------Reverse------------------------------------*/
+
+type part struct {
+	literal string
+	name    string
+	expr    *regexp.Regexp
+}
+
+// reverse builds paths for a route from params, in place of the RegEx groups.
+type reverse struct {
+	expr  *regexp.Regexp
+	parts []part
+}
+
+func newReverse(rt Route) (*reverse, error) {
+	e, ok := unwrap(rt).(expressioner)
+	if !ok {
+		return nil, fmt.Errorf("Route can't be reversed: %s", rt.Path())
+	}
+	expr := e.expression()
+	re, err := syntax.Parse(expr, syntax.Perl)
+	if err != nil {
+		return nil, err
+	}
+
+	r := new(reverse)
+	r.expr = regexp.MustCompile(expr)
+	if err = r.walk(re); err != nil {
+		return nil, fmt.Errorf("Route can't be reversed: %s, %s", rt.Path(), err)
+	}
+	return r, nil
+}
+
+func (r *reverse) walk(re *syntax.Regexp) error {
+	switch re.Op {
+	case syntax.OpLiteral:
+		r.parts = append(r.parts, part{literal: string(re.Rune)})
+	case syntax.OpConcat:
+		for _, sub := range re.Sub {
+			if err := r.walk(sub); err != nil {
+				return err
+			}
+		}
+	case syntax.OpCapture:
+		expr, err := regexp.Compile("^(?:" + re.Sub[0].String() + ")$")
+		if err != nil {
+			return err
+		}
+		r.parts = append(r.parts, part{name: re.Name, expr: expr})
+	case syntax.OpEmptyMatch, syntax.OpBeginLine, syntax.OpEndLine, syntax.OpBeginText, syntax.OpEndText,
+		syntax.OpQuest, syntax.OpStar:
+		// nothing is written for anchors, or optional expressions
+	default:
+		return fmt.Errorf("unsupported expression `%s`", re)
+	}
+	return nil
+}
+
+// build returns the escaped path for the given params.
+func (r *reverse) build(params ...interface{}) (string, error) {
+	var (
+		buf bytes.Buffer
+		i   int
+	)
+	for _, p := range r.parts {
+		if p.expr == nil {
+			buf.WriteString(p.literal)
+			continue
+		}
+		if i >= len(params) {
+			return "", fmt.Errorf("Missing param %d (%s)", i+1, p.name)
+		}
+
+		v := fmt.Sprint(params[i])
+		if !p.expr.MatchString(v) {
+			return "", fmt.Errorf("Param %d (%s) doesn't match `%s`: %s", i+1, p.name, p.expr, v)
+		}
+		buf.WriteString(v)
+		i++
+	}
+	if i != len(params) {
+		return "", fmt.Errorf("Too many params, expected %d, got %d", i, len(params))
+	}
+
+	path := buf.String()
+	if !r.expr.MatchString(path) {
+		return "", fmt.Errorf("Path doesn't match `%s`: %s", r.expr, path)
+	}
+	return (&url.URL{Path: path}).EscapedPath(), nil
+}
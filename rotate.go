@@ -0,0 +1,102 @@
+// Copyright 2013 Justin Wilson. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// +build !appengine
+
+package dingo
+
+import (
+	"fmt"
+	"os"
+	"sync"
+)
+
+// RotatingFile is a file writer that rotates the file once it reaches a
+// maximum size, keeping a number of backups named `<path>.1`, `<path>.2`, etc.
+type RotatingFile struct {
+	mu      sync.Mutex
+	path    string
+	maxSize int64
+	backups int
+	file    *os.File
+	size    int64
+}
+
+// NewRotatingFile opens, or creates, the file at path for appending, rotating
+// it once it reaches maxSize bytes and keeping at most the given number of backups.
+func NewRotatingFile(path string, maxSize int64, backups int) (*RotatingFile, error) {
+	r := &RotatingFile{path: path, maxSize: maxSize, backups: backups}
+	if err := r.open(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+func (r *RotatingFile) open() error {
+	f, err := os.OpenFile(r.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return err
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return err
+	}
+	r.file, r.size = f, info.Size()
+	return nil
+}
+
+// rotate moves the file to the first backup, and opens a new file. The current
+// file is only closed once the new file is open, so it remains writable when
+// rotating fails.
+func (r *RotatingFile) rotate() error {
+	if r.backups < 1 {
+		if err := os.Remove(r.path); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	} else {
+		for i := r.backups - 1; i > 0; i-- {
+			old := fmt.Sprintf("%s.%d", r.path, i)
+			if err := os.Rename(old, fmt.Sprintf("%s.%d", r.path, i+1)); err != nil && !os.IsNotExist(err) {
+				return err
+			}
+		}
+		if err := os.Rename(r.path, r.path+".1"); err != nil {
+			return err
+		}
+	}
+
+	old, size := r.file, r.size
+	if err := r.open(); err != nil {
+		r.file, r.size = old, size
+		return err
+	}
+	return old.Close()
+}
+
+// Write writes the data to the file, rotating the file first when the data
+// would take it past it's maximum size. When rotating fails the data is still
+// written to the current file, with the rotate error returned.
+func (r *RotatingFile) Write(data []byte) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var rerr error
+	if r.size > 0 && r.size+int64(len(data)) > r.maxSize {
+		rerr = r.rotate()
+	}
+	n, err := r.file.Write(data)
+	r.size += int64(n)
+	if err == nil {
+		err = rerr
+	}
+	return n, err
+}
+
+// Close closes the file.
+func (r *RotatingFile) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.file.Close()
+}
@@ -0,0 +1,133 @@
+// Copyright 2013 Justin Wilson. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// +build !appengine
+
+package dingo
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+/*-------------------------Helpers-------------------------*/
+func rotatingFile(t *testing.T, maxSize int64, backups int) (string, *RotatingFile) {
+	dir, err := ioutil.TempDir("", "dingo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(dir, "access.log")
+	r, err := NewRotatingFile(path, maxSize, backups)
+	if err != nil {
+		os.RemoveAll(dir)
+		t.Fatal(err)
+	}
+	return path, r
+}
+
+func writeLines(t *testing.T, r *RotatingFile, lines ...string) {
+	for _, l := range lines {
+		if _, err := r.Write([]byte(l)); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func fileContents(t *testing.T, files map[string]string) {
+	for path, expected := range files {
+		b, err := ioutil.ReadFile(path)
+		if expected == "" {
+			if !os.IsNotExist(err) {
+				t.Errorf("File (%s) exists: %q, %v", path, b, err)
+			}
+		} else if string(b) != expected {
+			t.Errorf("Unexpected contents of (%s): %q != %q, %v", path, b, expected, err)
+		}
+	}
+}
+
+/*-------------------------Tests-------------------------*/
+func TestRotatingFile(t *testing.T) {
+	path, r := rotatingFile(t, 4, 2)
+	defer os.RemoveAll(filepath.Dir(path))
+	defer r.Close()
+
+	// a write never rotates an empty file, even when larger than maxSize
+	writeLines(t, r, "aaaaa", "bb", "cc", "dd", "e")
+	fileContents(t, map[string]string{
+		path:        "dde",
+		path + ".1": "bbcc",
+		path + ".2": "aaaaa",
+		path + ".3": "",
+	})
+
+	// backups are shifted, dropping the oldest
+	writeLines(t, r, "ff")
+	fileContents(t, map[string]string{
+		path:        "ff",
+		path + ".1": "dde",
+		path + ".2": "bbcc",
+		path + ".3": "",
+	})
+}
+
+func TestRotatingFileNoBackups(t *testing.T) {
+	path, r := rotatingFile(t, 4, 0)
+	defer os.RemoveAll(filepath.Dir(path))
+	defer r.Close()
+
+	writeLines(t, r, "aaa", "b", "cc")
+	fileContents(t, map[string]string{
+		path:        "cc",
+		path + ".1": "",
+	})
+}
+
+func TestRotatingFileReopen(t *testing.T) {
+	path, r := rotatingFile(t, 4, 1)
+	defer os.RemoveAll(filepath.Dir(path))
+	writeLines(t, r, "aaa")
+	r.Close()
+
+	r, err := NewRotatingFile(path, 4, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+
+	// the size of the existing file counts towards maxSize
+	writeLines(t, r, "b", "cc")
+	fileContents(t, map[string]string{
+		path:        "cc",
+		path + ".1": "aaab",
+	})
+}
+
+func TestRotatingFileFailure(t *testing.T) {
+	path, r := rotatingFile(t, 4, 1)
+	defer os.RemoveAll(filepath.Dir(path))
+	defer r.Close()
+
+	// a directory in the way of the backup fails the rotate
+	if err := os.MkdirAll(filepath.Join(path+".1", "blocked"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	writeLines(t, r, "aaa")
+	if _, err := r.Write([]byte("bb")); err == nil {
+		t.Error("Failed rotate didn't return an error")
+	}
+	fileContents(t, map[string]string{path: "aaabb"})
+
+	// the file remains writable, rotating once the backup is clear
+	if err := os.RemoveAll(path + ".1"); err != nil {
+		t.Fatal(err)
+	}
+	writeLines(t, r, "cc")
+	fileContents(t, map[string]string{
+		path:        "cc",
+		path + ".1": "aaabb",
+	})
+}
@@ -5,6 +5,8 @@
 package dingo
 
 import (
+	"fmt"
+	"net/http"
 	"reflect"
 	"regexp"
 )
@@ -46,6 +48,10 @@ func (r *route) Execute(ctx Context) {
 	r.handler(ctx)
 }
 
+func (r *route) prefix() (string, bool) {
+	return r.path, true
+}
+
 /*----------regexp----------*/
 
 type reRoute struct {
@@ -100,15 +106,25 @@ func (r *reRoute) Execute(ctx Context) {
 	r.handler(ctx)
 }
 
+func (r *reRoute) prefix() (string, bool) {
+	return literalPrefix(r.expr), false
+}
+
 /*---------regexp2----------*/
 
 type rRoute struct {
 	path    string
 	expr    *regexp.Regexp
 	handler reflect.Value
+	args    []converter
 }
 
 // NewRRoute returns a new RegEx route for the given path/handler.
+// The handler must be a func accepting a Context followed by a param for each
+// RegEx group. Params may be strings, ints, uints, floats, bools, `time.Time`
+// or types implementing `encoding.TextUnmarshaler`; groups which can't be
+// converted result in a 400.
+// Panics when the handler's params don't match the RegEx groups.
 func NewRRoute(re string, handler interface{}) Route {
 	r := new(rRoute)
 	r.path = re
@@ -119,10 +135,31 @@ func NewRRoute(re string, handler interface{}) Route {
 	} else {
 		r.handler = reflect.ValueOf(handler)
 	}
+	r.args = converters(re, r.handler, r.expr.NumSubexp())
 
 	return r
 }
 
+func converters(re string, fn reflect.Value, groups int) []converter {
+	if fn.Kind() != reflect.Func {
+		panic(fmt.Sprintf("Handler is invalid: %v", fn))
+	}
+	t := fn.Type()
+	if t.IsVariadic() || t.NumIn() != groups+1 {
+		panic(fmt.Sprintf("Handler for route (%s) must accept a Context and %d params: %v", re, groups, t))
+	} else if t.In(0) != contextType {
+		panic(fmt.Sprintf("Handler for route (%s) must accept a Context as it's first param: %v", re, t))
+	}
+
+	args := make([]converter, groups)
+	for i := range args {
+		if args[i] = newConverter(t.In(i + 1)); args[i] == nil {
+			panic(fmt.Sprintf("Handler for route (%s) has an unsupported param type: %v", re, t.In(i+1)))
+		}
+	}
+	return args
+}
+
 // Returns the path of the route.
 func (r *rRoute) Path() string {
 	return r.path
@@ -140,11 +177,19 @@ func (r *rRoute) Matches(url string) bool {
 
 // Execute invokes the handler, passing in the RegEx groups, for this route, writing it's response.
 func (r *rRoute) Execute(ctx Context) {
-	// TODO it would be nice if we could detect numbers and cast them as such prior to invoking the func
 	args := []reflect.Value{reflect.ValueOf(ctx)}
 	matches := r.expr.FindStringSubmatch(ctx.URL.Path)
-	for _, a := range matches[1:] {
-		args = append(args, reflect.ValueOf(a))
+	for i, a := range matches[1:] {
+		v, err := r.args[i](a)
+		if err != nil {
+			ctx.HttpError(http.StatusBadRequest)
+			return
+		}
+		args = append(args, v)
 	}
 	r.handler.Call(args)
 }
+
+func (r *rRoute) prefix() (string, bool) {
+	return literalPrefix(r.expr), false
+}
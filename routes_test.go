@@ -8,7 +8,10 @@ import (
 	"bytes"
 	"fmt"
 	"net/http"
+	"net/http/httptest"
 	"net/url"
+	"strings"
+	"time"
 
 	"testing"
 )
@@ -58,6 +61,7 @@ func rroutes(m map[rePath]interface{}) map[string]Route {
 func dummyRequest(path string) *http.Request {
 	req := &http.Request{Method: "GET"}
 	req.URL, _ = url.Parse("http://www.juzt.in" + path)
+	req.Host = req.URL.Host
 	return req
 }
 
@@ -204,3 +208,167 @@ func TestRRRouteExecute(t *testing.T) {
 		}
 	}
 }
+
+var prMatchCol = map[rePath]map[string]string{
+	rePath{"/blog/:year/:slug/", "/blog/2013/some-post/"}:    {"year": "2013", "slug": "some-post"},
+	rePath{"/users/{id:int}/", "/users/-42/"}:                {"id": "-42"},
+	rePath{"/files/*rest", "/files/a/b/c/"}:                  {"rest": "a/b/c/"},
+	rePath{"/code/{code:[A-Z]{3}}/", "/code/ABC/"}:           {"code": "ABC"},
+	rePath{"/{slug}.html/", "/index.html/"}:                  {"slug": "index"},
+	rePath{"/posts/{id:uint}/{slug:slug}/", "/posts/7/a-b/"}: {"id": "7", "slug": "a-b"},
+	rePath{"/blog/:year/:slug", "/blog/2020/hello/"}:         {"year": "2020", "slug": "hello"},
+	rePath{"/café/:x/", "/café/1/"}:                          {"x": "1"},
+}
+var prNoMatchCol = map[rePath]Handler{
+	rePath{"/blog/:year/:slug/", "/blog/2013/"}:        dummyHandler,
+	rePath{"/users/{id:int}/", "/users/abc/"}:          dummyHandler,
+	rePath{"/code/{code:[A-Z]{3}}/", "/code/ABCD/"}:    dummyHandler,
+	rePath{"/{slug}.html/", "/indexxhtml/"}:            dummyHandler,
+	rePath{"/posts/{id:uint}/{slug:slug}/", "/posts/"}: dummyHandler,
+}
+
+func TestPRouteMatches(t *testing.T) {
+	for p, data := range prMatchCol {
+		var routeData map[string]string
+		r := NewPRoute(p.ReExp, func(ctx Context) { routeData = ctx.RouteData })
+		if !r.Matches(p.Path) {
+			t.Errorf("Route doesn't match it's path: (%s) != (%s)", r.Path(), p.Path)
+			continue
+		}
+
+		ctx := *new(Context)
+		ctx.Request = dummyRequest(p.Path)
+		r.Execute(ctx)
+		for k, v := range data {
+			if routeData[k] != v {
+				t.Errorf("Unexpected route data for (%s): %s=%s, expected %s", r.Path(), k, routeData[k], v)
+			}
+		}
+	}
+}
+
+func TestPRouteNoMatches(t *testing.T) {
+	for p, h := range prNoMatchCol {
+		if r := NewPRoute(p.ReExp, h); r.Matches(p.Path) {
+			t.Errorf("Route matches invalid path: (%s) == (%s)", r.Path(), p.Path)
+		}
+	}
+}
+
+func TestPRouteServe(t *testing.T) {
+	s := New(nil)
+	s.PRoute("/blog/:year/:slug", func(ctx Context) {
+		wc(ctx, ctx.RouteData["year"]+" "+ctx.RouteData["slug"])
+	}, "GET")
+
+	if w := serve(&s, "GET", "/blog/2020/hello"); w.Code != http.StatusMovedPermanently || w.Header().Get("Location") != "http://www.juzt.in/blog/2020/hello/" {
+		t.Errorf("Unexpected canonical redirect: %d, %s", w.Code, w.Header().Get("Location"))
+	}
+	if w := serve(&s, "GET", "/blog/2020/hello/"); w.Code != http.StatusOK || w.Body.String() != "2020 hello" {
+		t.Errorf("Unexpected response: %d, %s", w.Code, w.Body.String())
+	}
+}
+
+var urlCol = []struct {
+	route  Route
+	params []interface{}
+	url    string
+}{
+	{NewSRoute("/blog/", dummyHandler), nil, "/blog/"},
+	{NewReRoute("^/blog/(?P<year>\\d{4})/(?P<slug>[\\w-]+)/$", dummyHandler), []interface{}{2013, "a-post"}, "/blog/2013/a-post/"},
+	{NewRRoute("^/a/(.*)/(b|c)/$", dummyHandler_2), []interface{}{"x y", "c"}, "/a/x%20y/c/"},
+	{NewPRoute("/users/{id:int}/*rest", dummyHandler), []interface{}{42, "a/b/"}, "/users/42/a/b/"},
+	{WrapRoute(NewPRoute("/posts/:slug/", dummyHandler), tagMiddleware("a")), []interface{}{"abc"}, "/posts/abc/"},
+}
+
+var urlErrCol = []struct {
+	route  Route
+	params []interface{}
+}{
+	{NewSRoute("/blog/", dummyHandler), []interface{}{1}},
+	{NewReRoute("^/blog/(?P<year>\\d{4})/$", dummyHandler), []interface{}{"abcd"}},
+	{NewReRoute("^/blog/(?P<year>\\d{4})/$", dummyHandler), nil},
+	{NewPRoute("/users/{id:int}/", dummyHandler), []interface{}{"1/2"}},
+}
+
+func TestURL(t *testing.T) {
+	s := New(nil)
+	for i, u := range urlCol {
+		name := fmt.Sprint("route", i)
+		s.NamedRoute(name, u.route, "GET")
+		if url, err := s.URL(name, u.params...); err != nil {
+			t.Errorf("Failed to build url for (%s): %s", u.route.Path(), err)
+		} else if url != u.url {
+			t.Errorf("Unexpected url for (%s): %s != %s", u.route.Path(), url, u.url)
+		}
+	}
+
+	for i, u := range urlErrCol {
+		name := fmt.Sprint("err", i)
+		s.Name(name, u.route)
+		if url, err := s.URL(name, u.params...); err == nil {
+			t.Errorf("Expected error building url for (%s): %s", u.route.Path(), url)
+		}
+	}
+
+	s.SRouter("/named/").Name("named").Get(dummyHandler).Post(dummyHandler)
+	if url, err := s.URL("named"); err != nil || url != "/named/" {
+		t.Errorf("Unexpected url for named Router: %s, %v", url, err)
+	}
+}
+
+type upper string
+
+func (u *upper) UnmarshalText(text []byte) error {
+	*u = upper(strings.ToUpper(string(text)))
+	return nil
+}
+
+func typedHandler(ctx Context, i int, u uint8, f float64, b bool, d time.Time, s upper) {
+	wc(ctx, fmt.Sprintf("%d|%d|%v|%v|%s|%s", i, u, f, b, d.Format("2006-01-02"), s))
+}
+
+func TestRRouteTypedArgs(t *testing.T) {
+	r := NewRRoute("^/(-?\\d+)/(\\d+)/([\\d.]+)/(\\w+)/([\\d-]+)/(\\w+)/$", typedHandler)
+
+	w := httptest.NewRecorder()
+	ctx := NewContext(w, dummyRequest("/-4/8/1.5/true/2013-05-09/abc/"))
+	r.Execute(ctx)
+	if body := w.Body.String(); body != "-4|8|1.5|true|2013-05-09|ABC" {
+		t.Errorf("Handler recieved unexpected args: %s", body)
+	}
+
+	w = httptest.NewRecorder()
+	ctx = NewContext(w, dummyRequest("/-4/256/1.5/true/2013-05-09/abc/"))
+	r.Execute(ctx)
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("Unexpected status for invalid arg: %d != 400", w.Code)
+	}
+}
+
+func TestRRouteInvalidHandler(t *testing.T) {
+	invalid := []rePath{
+		{"^/(.*)/$", "dummyHandler_2"},
+		{"^/(.*)/(.*)/$", "dummyHandler_1"},
+		{"^/(.*)/$", "chan"},
+		{"^/(.*)/$", "no context"},
+		{"^/$", "not a func"},
+	}
+	handlers := map[string]interface{}{
+		"dummyHandler_2": dummyHandler_2,
+		"dummyHandler_1": dummyHandler_1,
+		"chan":           func(ctx Context, c chan int) {},
+		"no context":     func(s string, ctx Context) {},
+		"not a func":     "not a func",
+	}
+	for _, p := range invalid {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("Expected panic for invalid handler: (%s) %s", p.ReExp, p.Path)
+				}
+			}()
+			NewRRoute(p.ReExp, handlers[p.Path])
+		}()
+	}
+}
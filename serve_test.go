@@ -0,0 +1,134 @@
+// Copyright 2013 Justin Wilson. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// +build !appengine
+
+package dingo
+
+import (
+	"context"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestShutdown(t *testing.T) {
+	l, err := HTTPListener("127.0.0.1", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	started, release := make(chan bool), make(chan bool)
+	s := New(l)
+	s.SRoute("/slow/", func(ctx Context) {
+		started <- true
+		<-release
+		wc(ctx, "done")
+	}, "GET")
+
+	served := make(chan error)
+	go func() { served <- s.Serve() }()
+
+	body := make(chan string)
+	go func() {
+		res, err := http.Get("http://" + l.Addr().String() + "/slow/")
+		if err != nil {
+			body <- err.Error()
+			return
+		}
+		defer res.Body.Close()
+		b, _ := ioutil.ReadAll(res.Body)
+		body <- string(b)
+	}()
+
+	<-started
+	shutdown := make(chan error)
+	go func() { shutdown <- s.Shutdown(context.Background()) }()
+	time.Sleep(10 * time.Millisecond)
+	close(release)
+
+	if b := <-body; b != "done" {
+		t.Errorf("In-flight request wasn't drained: %s", b)
+	}
+	if err := <-shutdown; err != nil {
+		t.Errorf("Unexpected shutdown error: %s", err)
+	}
+	if err := <-served; err != nil {
+		t.Errorf("Unexpected serve error: %s", err)
+	}
+}
+
+func TestMultipleListeners(t *testing.T) {
+	dir, err := ioutil.TempDir("", "dingo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	tcp, err := HTTPListener("127.0.0.1", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sock := filepath.Join(dir, "dingo.sock")
+	unix, err := SOCKListener(sock, 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s := New(tcp)
+	s.AddListener(unix)
+	s.SRoute("/multi/", func(ctx Context) { wc(ctx, "multi") }, "GET")
+
+	served := make(chan error)
+	go func() { served <- s.Serve() }()
+
+	clients := map[string]*http.Client{
+		"tcp": http.DefaultClient,
+		"unix": &http.Client{Transport: &http.Transport{
+			DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+				return net.Dial("unix", sock)
+			},
+		}},
+	}
+	for name, c := range clients {
+		res, err := c.Get("http://" + tcp.Addr().String() + "/multi/")
+		if err != nil {
+			t.Errorf("Request over %s failed: %s", name, err)
+			continue
+		}
+		b, _ := ioutil.ReadAll(res.Body)
+		res.Body.Close()
+		if string(b) != "multi" {
+			t.Errorf("Unexpected response over %s: %s", name, b)
+		}
+	}
+
+	if err := s.Shutdown(context.Background()); err != nil {
+		t.Errorf("Unexpected shutdown error: %s", err)
+	}
+	if err := <-served; err != nil {
+		t.Errorf("Unexpected serve error: %s", err)
+	}
+}
+
+func TestListenerErrors(t *testing.T) {
+	l, err := HTTPListener("127.0.0.1", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	l.Close()
+
+	s := New(l)
+	errs, ok := s.Serve().(ListenerErrors)
+	if !ok || len(errs) != 1 || errs[0].Listener != l {
+		t.Errorf("Unexpected serve errors: %v", errs)
+	}
+	if s := New(); s.Serve() == nil {
+		t.Error("Serve without listeners didn't fail")
+	}
+}
@@ -0,0 +1,39 @@
+// Copyright 2013 Justin Wilson. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// +build !appengine
+
+package dingo
+
+import (
+	"context"
+	"log"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+)
+
+// ShutdownOnSignal shuts the server down upon receiving any of the given
+// signals, defaulting to SIGINT and SIGTERM, allowing in-flight requests the
+// given timeout to finish.
+func (s *Server) ShutdownOnSignal(timeout time.Duration, sigs ...os.Signal) {
+	if len(sigs) == 0 {
+		sigs = []os.Signal{os.Interrupt, syscall.SIGTERM}
+	}
+	c := make(chan os.Signal, 1)
+	signal.Notify(c, sigs...)
+
+	go func() {
+		sig := <-c
+		signal.Stop(c)
+		log.Printf("dingo: received %s, shutting down\n", sig)
+
+		ctx, cancel := context.WithTimeout(context.Background(), timeout)
+		defer cancel()
+		if err := s.Shutdown(ctx); err != nil {
+			log.Println("dingo: shutdown error, ", err)
+		}
+	}()
+}
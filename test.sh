@@ -2,3 +2,7 @@
 
 go test code.minty.io/dingo
 go test code.minty.io/dingo/views
+go test code.minty.io/dingo/request
+go test code.minty.io/dingo/rest
+go test code.minty.io/dingo/bind
+go test code.minty.io/dingo/validate
@@ -0,0 +1,201 @@
+// Copyright 2013 Justin Wilson. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// +build !appengine
+
+package dingo
+
+import (
+	"crypto/tls"
+	"errors"
+	"log"
+	"net"
+	"os"
+	"sync"
+	"time"
+)
+
+// Cert is a certificate, and it's key, file pair.
+type Cert struct {
+	CertFile, KeyFile string
+}
+
+// TLSOptions configures the listener returned by `TLSOptionsListener`.
+type TLSOptions struct {
+	// Certs are selected by the server name (SNI) requested by the client,
+	// the first being used when no other matches.
+	Certs []Cert
+	// MinVersion is the minimum TLS version, defaulting to TLS 1.2.
+	MinVersion uint16
+	// CipherSuites, when set, limits the cipher suites of TLS 1.2 and earlier.
+	CipherSuites []uint16
+	// DisableHTTP2 disables negotiating HTTP/2.
+	DisableHTTP2 bool
+	// ReloadInterval is how often the cert files are checked for changes,
+	// being reloaded when changed. Zero disables reloading.
+	ReloadInterval time.Duration
+}
+
+func (o TLSOptions) config() (*tls.Config, *certStore, error) {
+	if len(o.Certs) == 0 {
+		return nil, nil, errors.New("dingo: no TLS certificates given")
+	}
+	store := &certStore{pairs: o.Certs}
+	if err := store.load(); err != nil {
+		return nil, nil, err
+	}
+	if o.ReloadInterval > 0 {
+		store.watch(o.ReloadInterval)
+	}
+
+	config := &tls.Config{
+		GetCertificate: store.certificate,
+		MinVersion:     o.MinVersion,
+		CipherSuites:   o.CipherSuites,
+		NextProtos:     []string{"h2", "http/1.1"},
+	}
+	if config.MinVersion == 0 {
+		config.MinVersion = tls.VersionTLS12
+	}
+	if o.DisableHTTP2 {
+		config.NextProtos = []string{"http/1.1"}
+	}
+	return config, store, nil
+}
+
+/*---------------------------------Cert Store---------------------------------*/
+
+// certStore holds the loaded certificates, reloading them when their files change.
+type certStore struct {
+	mu       sync.RWMutex
+	pairs    []Cert
+	certs    []*tls.Certificate
+	modified []time.Time
+	done     chan struct{}
+	stopOnce sync.Once
+}
+
+// modTime returns the latest modification time of the cert/key pair.
+func modTime(c Cert) (time.Time, error) {
+	var t time.Time
+	for _, f := range []string{c.CertFile, c.KeyFile} {
+		info, err := os.Stat(f)
+		if err != nil {
+			return t, err
+		}
+		if info.ModTime().After(t) {
+			t = info.ModTime()
+		}
+	}
+	return t, nil
+}
+
+func (s *certStore) load() error {
+	certs := make([]*tls.Certificate, len(s.pairs))
+	modified := make([]time.Time, len(s.pairs))
+	for i, p := range s.pairs {
+		t, err := modTime(p)
+		if err != nil {
+			return err
+		}
+		cert, err := tls.LoadX509KeyPair(p.CertFile, p.KeyFile)
+		if err != nil {
+			return err
+		}
+		certs[i], modified[i] = &cert, t
+	}
+
+	s.mu.Lock()
+	s.certs, s.modified = certs, modified
+	s.mu.Unlock()
+	return nil
+}
+
+// reload reloads the pairs whose files have changed, keeping the current
+// certificate of any pair that fails to load.
+func (s *certStore) reload() {
+	for i, p := range s.pairs {
+		t, err := modTime(p)
+		s.mu.RLock()
+		changed := err == nil && !t.Equal(s.modified[i])
+		s.mu.RUnlock()
+		if !changed {
+			continue
+		}
+
+		cert, err := tls.LoadX509KeyPair(p.CertFile, p.KeyFile)
+		if err != nil {
+			log.Printf("dingo: failed to reload certificate %s, %s\n", p.CertFile, err)
+			continue
+		}
+		s.mu.Lock()
+		s.certs[i], s.modified[i] = &cert, t
+		s.mu.Unlock()
+	}
+}
+
+func (s *certStore) watch(interval time.Duration) {
+	s.done = make(chan struct{})
+	go func() {
+		t := time.NewTicker(interval)
+		defer t.Stop()
+		for {
+			select {
+			case <-t.C:
+				s.reload()
+			case <-s.done:
+				return
+			}
+		}
+	}()
+}
+
+func (s *certStore) stop() {
+	if s == nil || s.done == nil {
+		return
+	}
+	s.stopOnce.Do(func() { close(s.done) })
+}
+
+// certificate returns the certificate for the server name requested by the client.
+func (s *certStore) certificate(hello *tls.ClientHelloInfo) (*tls.Certificate, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if hello.ServerName != "" {
+		for _, c := range s.certs {
+			if hello.SupportsCertificate(c) == nil {
+				return c, nil
+			}
+		}
+	}
+	return s.certs[0], nil
+}
+
+/*--------------------------------TLS Listener--------------------------------*/
+
+// tlsListener accepts TLS connections from the wrapped listener.
+type tlsListener struct {
+	net.Listener
+	config *tls.Config
+	store  *certStore
+}
+
+// Accept waits for, and returns, the next TLS connection.
+func (l *tlsListener) Accept() (net.Conn, error) {
+	c, err := l.Listener.Accept()
+	if err != nil {
+		return nil, err
+	}
+	return tls.Server(c, l.config), nil
+}
+
+// Close stops reloading certificates and closes the listener.
+func (l *tlsListener) Close() error {
+	l.store.stop()
+	return l.Listener.Close()
+}
+
+func (l *tlsListener) unwrapListener() net.Listener {
+	return l.Listener
+}
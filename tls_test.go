@@ -0,0 +1,113 @@
+// Copyright 2013 Justin Wilson. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// +build !appengine
+
+package dingo
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io/ioutil"
+	"math/big"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+/*-------------------------Helpers-------------------------*/
+func writeCert(t *testing.T, dir, host string, serial int64) Cert {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(serial),
+		Subject:      pkix.Name{CommonName: host},
+		DNSNames:     []string{host},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDer, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	c := Cert{filepath.Join(dir, host+".crt"), filepath.Join(dir, host+".key")}
+	ioutil.WriteFile(c.CertFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0600)
+	ioutil.WriteFile(c.KeyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDer}), 0600)
+
+	// ensure the change is seen regardless of the file system's time resolution
+	mod := time.Now().Add(time.Duration(serial) * time.Second)
+	os.Chtimes(c.CertFile, mod, mod)
+	os.Chtimes(c.KeyFile, mod, mod)
+	return c
+}
+
+func peerCert(t *testing.T, addr, host string) (*x509.Certificate, string) {
+	tr := &http.Transport{
+		TLSClientConfig:   &tls.Config{ServerName: host, InsecureSkipVerify: true},
+		ForceAttemptHTTP2: true,
+	}
+	defer tr.CloseIdleConnections()
+
+	res, err := (&http.Client{Transport: tr}).Get("https://" + addr + "/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	res.Body.Close()
+	return res.TLS.PeerCertificates[0], res.Proto
+}
+
+/*-------------------------Tests-------------------------*/
+func TestTLSListener(t *testing.T) {
+	dir, err := ioutil.TempDir("", "dingo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	a, b := writeCert(t, dir, "a.test", 1), writeCert(t, dir, "b.test", 2)
+	l, err := TLSOptionsListener("127.0.0.1", 0, TLSOptions{
+		Certs:          []Cert{a, b},
+		ReloadInterval: 10 * time.Millisecond,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := New(l)
+	s.SRoute("/", dummyHandler, "GET")
+	go s.Serve()
+	defer s.Shutdown(context.Background())
+
+	addr := l.Addr().String()
+	if c, proto := peerCert(t, addr, "b.test"); c.Subject.CommonName != "b.test" {
+		t.Errorf("Unexpected SNI certificate: %s", c.Subject.CommonName)
+	} else if proto != "HTTP/2.0" {
+		t.Errorf("HTTP/2 wasn't negotiated: %s", proto)
+	}
+	if c, _ := peerCert(t, addr, "unknown.test"); c.Subject.CommonName != "a.test" {
+		t.Errorf("Unexpected default certificate: %s", c.Subject.CommonName)
+	}
+
+	writeCert(t, dir, "a.test", 3)
+	time.Sleep(50 * time.Millisecond)
+	if c, _ := peerCert(t, addr, "a.test"); c.SerialNumber.Int64() != 3 {
+		t.Errorf("Certificate wasn't reloaded: %d", c.SerialNumber)
+	}
+}
@@ -0,0 +1,180 @@
+// Copyright 2013 Justin Wilson. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package dingo
+
+import (
+	"regexp"
+	"regexp/syntax"
+	"sort"
+	"strings"
+)
+
+/*----------------------------------Prefix------------------------------------*/
+
+// prefixer is implemented by routes that know the literal prefix every
+// matching path must begin with. When exact is true the route only matches
+// the prefix itself.
+type prefixer interface {
+	prefix() (p string, exact bool)
+}
+
+// routePrefix returns the literal prefix for the given route, or an empty
+// prefix when the route can't be indexed.
+func routePrefix(rt Route) (string, bool) {
+	if p, ok := rt.(prefixer); ok {
+		return p.prefix()
+	}
+	return "", false
+}
+
+// literalPrefix returns the literal prefix of a start anchored expression, the
+// literals following the `^` of it's leading concatenation.
+// Unanchored expressions can match anywhere within a path, so have no prefix.
+func literalPrefix(expr *regexp.Regexp) string {
+	re, err := syntax.Parse(expr.String(), syntax.Perl)
+	if err != nil || re.Op != syntax.OpConcat {
+		return ""
+	}
+	if op := re.Sub[0].Op; op != syntax.OpBeginText && op != syntax.OpBeginLine {
+		return ""
+	}
+
+	var p strings.Builder
+	for _, sub := range re.Sub[1:] {
+		if sub.Op != syntax.OpLiteral || sub.Flags&syntax.FoldCase != 0 {
+			break
+		}
+		p.WriteString(string(sub.Rune))
+	}
+	return p.String()
+}
+
+/*-----------------------------------Tree-------------------------------------*/
+
+type entry struct {
+	index int
+	exact bool
+	route Route
+}
+
+type node struct {
+	path     string
+	children []*node
+	entries  []entry
+}
+
+// tree is a Routes collection indexing routes by their literal prefix within
+// a radix tree. Only routes sharing a prefix with the requested path are
+// checked, in the order they were added, so the first match wins just as it
+// does for the linear collection.
+type tree struct {
+	root  node
+	count int
+}
+
+// NewTreeRoutes returns a new, empty, prefix tree based Routes collection.
+func NewTreeRoutes() Routes {
+	return new(tree)
+}
+
+// NewListRoutes returns a new, empty, Routes collection that checks every
+// route in the order it was added.
+func NewListRoutes() Routes {
+	return new(routes)
+}
+
+// Add adds a new route to the collection.
+func (t *tree) Add(route Route) {
+	p, exact := routePrefix(route)
+	e := entry{t.count, exact, route}
+	t.count++
+
+	n := &t.root
+	for {
+		if len(p) == 0 {
+			n.entries = append(n.entries, e)
+			return
+		}
+
+		child := n.child(p[0])
+		if child == nil {
+			n.children = append(n.children, &node{path: p, entries: []entry{e}})
+			return
+		}
+
+		l := commonPrefix(p, child.path)
+		if l < len(child.path) {
+			// split the child at the shared prefix
+			split := &node{path: child.path[:l], children: []*node{child}}
+			n.replace(split)
+			child.path = child.path[l:]
+			child = split
+		}
+		n, p = child, p[l:]
+	}
+}
+
+// Route finds a Route for the given url, or nil.
+func (t *tree) Route(path string) (Route, bool) {
+	var candidates []entry
+
+	n, p := &t.root, path
+	for n != nil {
+		for _, e := range n.entries {
+			if !e.exact || len(p) == 0 {
+				candidates = append(candidates, e)
+			}
+		}
+		if len(p) == 0 {
+			break
+		}
+
+		next := n.child(p[0])
+		if next == nil || !strings.HasPrefix(p, next.path) {
+			break
+		}
+		n, p = next, p[len(next.path):]
+	}
+
+	sort.Sort(byIndex(candidates))
+	for _, e := range candidates {
+		if e.route.Matches(path) {
+			return e.route, true
+		}
+	}
+	return nil, false
+}
+
+func (n *node) child(b byte) *node {
+	for _, c := range n.children {
+		if c.path[0] == b {
+			return c
+		}
+	}
+	return nil
+}
+
+func (n *node) replace(c *node) {
+	for i, child := range n.children {
+		if child.path[0] == c.path[0] {
+			n.children[i] = c
+			return
+		}
+	}
+}
+
+func commonPrefix(a, b string) int {
+	i := 0
+	for i < len(a) && i < len(b) && a[i] == b[i] {
+		i++
+	}
+	return i
+}
+
+type byIndex []entry
+
+func (e byIndex) Len() int           { return len(e) }
+func (e byIndex) Less(i, j int) bool { return e[i].index < e[j].index }
+func (e byIndex) Swap(i, j int)      { e[i], e[j] = e[j], e[i] }
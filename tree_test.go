@@ -0,0 +1,106 @@
+// Copyright 2013 Justin Wilson. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package dingo
+
+import (
+	"regexp"
+	"testing"
+)
+
+/*-------------------------Data-------------------------*/
+var treeCol = []Route{
+	NewSRoute("/", dummyHandler),
+	NewSRoute("/blog/", dummyHandler),
+	NewReRoute("^/blog/(?P<year>\\d{4})/$", dummyHandler),
+	NewReRoute("^/blog/(.*)/$", dummyHandler),
+	NewSRoute("/blog/archive/", dummyHandler),
+	NewRRoute("^/bl(a|o)g/$", dummyHandler_1),
+	NewSRoute("/a/b/c/", dummyHandler),
+	NewReRoute("^/a/(.*)/$", dummyHandler),
+	NewReRoute("/anywhere/$", dummyHandler),
+	NewSRoute("/some-thing-cool/", dummyHandler),
+	NewSRoute("/some-thing/", dummyHandler),
+}
+
+var treePaths = []string{
+	"/",
+	"/blog/",
+	"/blog/2013/",
+	"/blog/archive/",
+	"/blog/some/post/",
+	"/blag/",
+	"/a/b/c/",
+	"/a/b/",
+	"/a/",
+	"/some-thing-cool/",
+	"/some-thing/",
+	"/some-thing-c/",
+	"/deep/down/anywhere/",
+	"/nope/",
+	"",
+}
+
+var prefixCol = map[string]string{
+	"^/blog/(.*)/$":             "/blog/",
+	"^/a/(.*)/$":                "/a/",
+	"^/bl(a|o)g/$":              "/bl",
+	"^/blog/(?P<year>\\d{4})/$": "/blog/",
+	"^/blogs?/$":                "/blog",
+	"^/café/(.*)$":              "/café/",
+	"^(?i)/blog/$":              "",
+	"/anywhere/$":               "",
+	"^/a/|^/b/":                 "",
+}
+
+/*-------------------------Tests-------------------------*/
+func TestTreeMatchesList(t *testing.T) {
+	list, tree := NewListRoutes(), NewTreeRoutes()
+	for _, r := range treeCol {
+		list.Add(r)
+		tree.Add(r)
+	}
+
+	for _, path := range treePaths {
+		lr, lok := list.Route(path)
+		tr, tok := tree.Route(path)
+		if lok != tok || lr != tr {
+			t.Errorf("Tree route differs from list route for (%s): %v != %v", path, tr, lr)
+		}
+	}
+}
+
+func TestTreeOrder(t *testing.T) {
+	first := NewReRoute("^/blog/(.*)/$", dummyHandler)
+	tree := NewTreeRoutes()
+	tree.Add(first)
+	tree.Add(NewSRoute("/blog/post/", dummyHandler))
+
+	if r, ok := tree.Route("/blog/post/"); !ok || r != first {
+		t.Errorf("Tree didn't return the first added route: %v", r)
+	}
+}
+
+func TestLiteralPrefix(t *testing.T) {
+	for expr, prefix := range prefixCol {
+		if p := literalPrefix(regexp.MustCompile(expr)); p != prefix {
+			t.Errorf("Unexpected literal prefix for (%s): %q != %q", expr, p, prefix)
+		}
+	}
+}
+
+func TestTreeIndexes(t *testing.T) {
+	tr := NewTreeRoutes().(*tree)
+	for _, expr := range []string{"^/blog/(.*)/$", "^/a/(.*)/$"} {
+		tr.Add(NewReRoute(expr, dummyHandler))
+	}
+	if len(tr.root.entries) != 0 {
+		t.Errorf("Anchored routes weren't indexed by their prefix: %d root entries", len(tr.root.entries))
+	}
+
+	tr.Add(NewReRoute("/anywhere/$", dummyHandler))
+	if len(tr.root.entries) != 1 {
+		t.Errorf("Unanchored route wasn't added to the root: %d root entries", len(tr.root.entries))
+	}
+}
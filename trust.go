@@ -0,0 +1,53 @@
+// Copyright 2013 Justin Wilson. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package dingo
+
+import (
+	"fmt"
+	"net"
+	"strings"
+)
+
+// trustedNets are the networks of trusted peers, such as proxies and balancers.
+type trustedNets []*net.IPNet
+
+// parseTrusted parses the given CIDRs, or single IPs.
+func parseTrusted(cidrs []string) (trustedNets, error) {
+	nets := make(trustedNets, 0, len(cidrs))
+	for _, c := range cidrs {
+		if !strings.Contains(c, "/") {
+			ip := net.ParseIP(c)
+			if ip == nil {
+				return nil, fmt.Errorf("dingo: invalid trusted IP %q", c)
+			}
+			bits := 8 * net.IPv6len
+			if ip4 := ip.To4(); ip4 != nil {
+				ip, bits = ip4, 8*net.IPv4len
+			}
+			nets = append(nets, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+			continue
+		}
+		_, n, err := net.ParseCIDR(c)
+		if err != nil {
+			return nil, fmt.Errorf("dingo: invalid trusted CIDR %q, %s", c, err)
+		}
+		nets = append(nets, n)
+	}
+	return nets, nil
+}
+
+// contains returns whether the given IP is within a trusted network.
+func (t trustedNets) contains(ip string) bool {
+	addr := net.ParseIP(ip)
+	if addr == nil {
+		return false
+	}
+	for _, n := range t {
+		if n.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
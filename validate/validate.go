@@ -0,0 +1,294 @@
+// Copyright 2013 Justin Wilson. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package validate validates structs according to their `validate` tags.
+//
+//	type Post struct {
+//		Title  string `validate:"required,max=80"`
+//		Author Author
+//		Email  string `validate:"omitempty,email"`
+//		State  string `validate:"oneof=draft published"`
+//		Slug   string `validate:"required,regex=^[a-z0-9-]+$"`
+//	}
+//
+// Rules are separated by commas, the `regex` rule must be last as it's param
+// may contain commas. Nested structs are validated. Rules apply to zero values,
+// so `min=1` fails an empty slice, unless following an `omitempty` rule, which
+// skips the remaining rules for zero values.
+package validate
+
+import (
+	"encoding/xml"
+	"fmt"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Func is a validation rule, returning whether the value is valid for the param.
+type Func func(v reflect.Value, param string) bool
+
+var (
+	rules = map[string]Func{
+		"required": required,
+		"min":      minimum,
+		"max":      maximum,
+		"len":      length,
+		"regex":    match,
+		"email":    email,
+		"oneof":    oneOf,
+	}
+	messages = map[string]string{
+		"required": "is required",
+		"min":      "must be at least %s",
+		"max":      "must be at most %s",
+		"len":      "must have a length of %s",
+		"regex":    "must match %s",
+		"email":    "must be an email address",
+		"oneof":    "must be one of %s",
+	}
+
+	emailExpr = regexp.MustCompile(`^[^@\s]+@[^@\s]+\.[^@\s]+$`)
+	exprs     = make(map[string]*regexp.Regexp)
+	exprsMu   sync.Mutex
+)
+
+// AddRule adds a rule usable within `validate` tags, with the message, which
+// may contain a `%s` for the param, used for it's field errors.
+func AddRule(name string, fn Func, message string) {
+	rules[name] = fn
+	messages[name] = message
+}
+
+/*-----------------------------------Errors-----------------------------------*/
+
+// FieldError is a single failed rule of a field.
+type FieldError struct {
+	Field   string `json:"field" xml:"field,attr"`
+	Rule    string `json:"rule" xml:"rule,attr"`
+	Param   string `json:"param,omitempty" xml:"param,attr,omitempty"`
+	Message string `json:"message" xml:",chardata"`
+}
+
+func (e FieldError) Error() string {
+	return e.Field + " " + e.Message
+}
+
+// Errors is the collection of field errors from validating a struct.
+type Errors []FieldError
+
+func (e Errors) Error() string {
+	msgs := make([]string, len(e))
+	for i, err := range e {
+		msgs[i] = err.Error()
+	}
+	return strings.Join(msgs, "; ")
+}
+
+// MarshalXML encodes the errors as `error` elements within an `errors` root,
+// so the errors form a single XML document.
+func (e Errors) MarshalXML(enc *xml.Encoder, start xml.StartElement) error {
+	start.Name = xml.Name{Local: "errors"}
+	return enc.EncodeElement(struct {
+		Errors []FieldError `xml:"error"`
+	}{e}, start)
+}
+
+/*----------------------------------Validate----------------------------------*/
+
+// Struct validates the given struct, or pointer to a struct, returning Errors
+// for any fields that fail their rules.
+// Panics when a tag contains an unknown rule.
+func Struct(s interface{}) error {
+	v := reflect.Indirect(reflect.ValueOf(s))
+	if v.Kind() != reflect.Struct {
+		panic(fmt.Sprintf("validate: not a struct: %T", s))
+	}
+
+	var errs Errors
+	validate(v, "", &errs)
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+func fieldName(f reflect.StructField) string {
+	if name := strings.Split(f.Tag.Get("json"), ",")[0]; name != "" && name != "-" {
+		return name
+	}
+	return f.Name
+}
+
+func validate(v reflect.Value, prefix string, errs *Errors) {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		f, fv := t.Field(i), v.Field(i)
+		if f.PkgPath != "" && !f.Anonymous {
+			// unexported
+			continue
+		}
+
+		name := prefix + fieldName(f)
+		if f.Anonymous {
+			name = strings.TrimSuffix(prefix, ".")
+		}
+		if tag := f.Tag.Get("validate"); tag != "" && tag != "-" {
+			if !check(fv, name, tag, errs) {
+				continue
+			}
+		}
+
+		// nested structs
+		if fv.Kind() == reflect.Ptr && !fv.IsNil() {
+			fv = fv.Elem()
+		}
+		if fv.Kind() == reflect.Struct {
+			if f.Anonymous {
+				validate(fv, prefix, errs)
+			} else {
+				validate(fv, name+".", errs)
+			}
+		}
+	}
+}
+
+// check applies the rules of the tag to the field, returning whether it's valid.
+func check(v reflect.Value, name, tag string, errs *Errors) bool {
+	valid := true
+	for len(tag) > 0 {
+		rule := tag
+		if !strings.HasPrefix(tag, "regex=") {
+			if i := strings.Index(tag, ","); i >= 0 {
+				rule, tag = tag[:i], tag[i+1:]
+			} else {
+				tag = ""
+			}
+		} else {
+			tag = ""
+		}
+
+		var param string
+		if i := strings.Index(rule, "="); i >= 0 {
+			rule, param = rule[:i], rule[i+1:]
+		}
+		if rule == "omitempty" {
+			if isZero(v) {
+				break
+			}
+			continue
+		}
+		fn, ok := rules[rule]
+		if !ok {
+			panic(fmt.Sprintf("validate: unknown rule `%s` for field %s", rule, name))
+		}
+
+		if !fn(v, param) {
+			msg := messages[rule]
+			if strings.Contains(msg, "%s") {
+				msg = fmt.Sprintf(msg, param)
+			}
+			*errs = append(*errs, FieldError{name, rule, param, msg})
+			valid = false
+			if rule == "required" {
+				break
+			}
+		}
+	}
+	return valid
+}
+
+/*-----------------------------------Rules------------------------------------*/
+
+func isZero(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Slice, reflect.Map, reflect.Array, reflect.String:
+		return v.Len() == 0
+	case reflect.Ptr, reflect.Interface:
+		return v.IsNil()
+	}
+	return v.IsZero()
+}
+
+func required(v reflect.Value, param string) bool {
+	return !isZero(v)
+}
+
+// size returns the length, or numeric value, of the value.
+func size(v reflect.Value) (float64, bool) {
+	v = reflect.Indirect(v)
+	switch v.Kind() {
+	case reflect.String:
+		return float64(len([]rune(v.String()))), true
+	case reflect.Slice, reflect.Map, reflect.Array:
+		return float64(v.Len()), true
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(v.Int()), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return float64(v.Uint()), true
+	case reflect.Float32, reflect.Float64:
+		return v.Float(), true
+	}
+	return 0, false
+}
+
+func compare(v reflect.Value, param string, fn func(s, p float64) bool) bool {
+	p, err := strconv.ParseFloat(param, 64)
+	if err != nil {
+		panic(fmt.Sprintf("validate: invalid param: %s", param))
+	}
+	s, ok := size(v)
+	return ok && fn(s, p)
+}
+
+func minimum(v reflect.Value, param string) bool {
+	return compare(v, param, func(s, p float64) bool { return s >= p })
+}
+
+func maximum(v reflect.Value, param string) bool {
+	return compare(v, param, func(s, p float64) bool { return s <= p })
+}
+
+func length(v reflect.Value, param string) bool {
+	return compare(v, param, func(s, p float64) bool { return s == p })
+}
+
+// str returns the value as a string, nil pointers being empty.
+func str(v reflect.Value) string {
+	v = reflect.Indirect(v)
+	if !v.IsValid() {
+		return ""
+	}
+	if v.Kind() == reflect.String {
+		return v.String()
+	}
+	return fmt.Sprint(v.Interface())
+}
+
+func match(v reflect.Value, param string) bool {
+	exprsMu.Lock()
+	expr, ok := exprs[param]
+	if !ok {
+		expr = regexp.MustCompile(param)
+		exprs[param] = expr
+	}
+	exprsMu.Unlock()
+	return expr.MatchString(str(v))
+}
+
+func email(v reflect.Value, param string) bool {
+	return emailExpr.MatchString(str(v))
+}
+
+func oneOf(v reflect.Value, param string) bool {
+	s := str(v)
+	for _, p := range strings.Fields(param) {
+		if s == p {
+			return true
+		}
+	}
+	return false
+}
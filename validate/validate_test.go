@@ -0,0 +1,117 @@
+// Copyright 2013 Justin Wilson. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package validate
+
+import (
+	"testing"
+)
+
+type author struct {
+	Name  string `validate:"required"`
+	Email string `json:"email" validate:"omitempty,email"`
+}
+
+type post struct {
+	Title  string   `json:"title" validate:"required,max=10"`
+	Slug   string   `validate:"omitempty,regex=^[a-z0-9,-]+$"`
+	State  string   `validate:"omitempty,oneof=draft published"`
+	Tags   []string `validate:"min=1,max=3"`
+	Rating int      `validate:"min=1,max=5"`
+	Code   string   `validate:"len=3"`
+	Author author
+	Editor *author
+}
+
+var validPost = post{
+	Title:  "Hello",
+	Slug:   "hello,world",
+	State:  "draft",
+	Tags:   []string{"a"},
+	Rating: 5,
+	Code:   "abc",
+	Author: author{"juztin", "juztin@example.com"},
+}
+
+func TestValid(t *testing.T) {
+	if err := Struct(validPost); err != nil {
+		t.Errorf("Unexpected errors: %s", err)
+	}
+	if err := Struct(&validPost); err != nil {
+		t.Errorf("Unexpected errors: %s", err)
+	}
+}
+
+func TestInvalid(t *testing.T) {
+	p := post{
+		Slug:   "Hello World",
+		State:  "deleted",
+		Tags:   []string{"a", "b", "c", "d"},
+		Rating: 9,
+		Code:   "ab",
+		Author: author{Email: "nope"},
+		Editor: &author{},
+	}
+	expected := []string{
+		"title:required",
+		"Slug:regex",
+		"State:oneof",
+		"Tags:max",
+		"Rating:max",
+		"Code:len",
+		"Author.Name:required",
+		"Author.email:email",
+		"Editor.Name:required",
+	}
+
+	errs, ok := Struct(p).(Errors)
+	if !ok || len(errs) != len(expected) {
+		t.Fatalf("Unexpected errors: %v", errs)
+	}
+	for i, e := range errs {
+		if e.Field+":"+e.Rule != expected[i] {
+			t.Errorf("Unexpected error: %s:%s != %s", e.Field, e.Rule, expected[i])
+		}
+	}
+}
+
+func TestZero(t *testing.T) {
+	expected := []string{"title:required", "Tags:min", "Rating:min", "Code:len", "Author.Name:required"}
+
+	errs, ok := Struct(post{}).(Errors)
+	if !ok || len(errs) != len(expected) {
+		t.Fatalf("Unexpected errors: %v", errs)
+	}
+	for i, e := range errs {
+		if e.Field+":"+e.Rule != expected[i] {
+			t.Errorf("Unexpected error: %s:%s != %s", e.Field, e.Rule, expected[i])
+		}
+	}
+}
+
+func TestNilPointer(t *testing.T) {
+	type contact struct {
+		Email *string `validate:"email"`
+		Phone *string `validate:"regex=^[0-9]+$"`
+		State *string `validate:"oneof=on off"`
+		Name  *string `validate:"min=1"`
+		Web   *string `validate:"omitempty,email"`
+	}
+	expected := []string{"Email:email", "Phone:regex", "State:oneof", "Name:min"}
+
+	errs, ok := Struct(contact{}).(Errors)
+	if !ok || len(errs) != len(expected) {
+		t.Fatalf("Unexpected errors: %v", errs)
+	}
+	for i, e := range errs {
+		if e.Field+":"+e.Rule != expected[i] {
+			t.Errorf("Unexpected error: %s:%s != %s", e.Field, e.Rule, expected[i])
+		}
+	}
+
+	email := "juztin@example.com"
+	if err := Struct(contact{Email: &email}); err == nil || len(err.(Errors)) != 3 {
+		t.Errorf("Unexpected errors for a set pointer: %v", err)
+	}
+}
@@ -0,0 +1,94 @@
+// Copyright 2013 Justin Wilson. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package dingo
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"time"
+)
+
+type contextKey int
+
+const valuesKey contextKey = iota
+
+// values holds the request scoped values set on a Context. It is stored within
+// the request's `context.Context` so is shared by every copy of the Context.
+type values struct {
+	sync.RWMutex
+	m map[interface{}]interface{}
+}
+
+func withValues(r *http.Request) *http.Request {
+	if r == nil {
+		return r
+	}
+	if _, ok := r.Context().Value(valuesKey).(*values); ok {
+		return r
+	}
+	return r.WithContext(context.WithValue(r.Context(), valuesKey, new(values)))
+}
+
+/*----------------------------------Values------------------------------------*/
+
+// Set stores a request scoped value, available to all middleware and handlers of the request.
+func (c *Context) Set(key, value interface{}) {
+	c.Request = withValues(c.Request)
+	v := c.Request.Context().Value(valuesKey).(*values)
+
+	v.Lock()
+	defer v.Unlock()
+	if v.m == nil {
+		v.m = make(map[interface{}]interface{})
+	}
+	v.m[key] = value
+}
+
+// Get returns a request scoped value, falling back to the values of the
+// request's `context.Context`.
+func (c *Context) Get(key interface{}) interface{} {
+	ctx := c.Request.Context()
+	if v, ok := ctx.Value(valuesKey).(*values); ok {
+		v.RLock()
+		value, ok := v.m[key]
+		v.RUnlock()
+		if ok {
+			return value
+		}
+	}
+	return ctx.Value(key)
+}
+
+/*--------------------------------Cancellation--------------------------------*/
+
+// Done returns a channel that's closed when the request is cancelled, either by
+// the client going away or by the route's deadline passing.
+func (c *Context) Done() <-chan struct{} {
+	return c.Request.Context().Done()
+}
+
+// Canceled returns whether the request has been cancelled.
+func (c *Context) Canceled() bool {
+	return c.Request.Context().Err() != nil
+}
+
+// Timeout returns middleware that cancels the request after the given duration.
+// Handlers doing long running work should watch `Context.Done()`. When the
+// deadline passes before the response has been started a 503 is issued.
+func Timeout(d time.Duration) Middleware {
+	return func(next Handler) Handler {
+		return func(ctx Context) {
+			c, cancel := context.WithTimeout(ctx.Request.Context(), d)
+			defer cancel()
+
+			ctx.Request = ctx.Request.WithContext(c)
+			next(ctx)
+			if c.Err() == context.DeadlineExceeded && !ctx.Written() {
+				ctx.HttpError(http.StatusServiceUnavailable)
+			}
+		}
+	}
+}
@@ -8,6 +8,7 @@ import (
 	"errors"
 	"fmt"
 	"log"
+	"net/http"
 	"reflect"
 	"strings"
 	"text/template"
@@ -17,6 +18,9 @@ import (
 
 var (
 	viewCol = make(map[string]View)
+	// URLServer is the server used by the `url` template func to build the
+	// paths of named routes.
+	URLServer *dingo.Server
 )
 
 // View wraps a template and provides CRUD operations, and nesting of templates.
@@ -52,11 +56,11 @@ func Execute(ctx dingo.Context, key string, data interface{}) {
 	} else if err := v.Execute(ctx, data); err != nil {
 		// TODO log this somewhere
 		log.Println("dingo: template execution error, ", err)
-		/* This will cause a warning to be logged from `net/http/server.go`.
-		 * The headers have, most likely, been written to the stream. The error is
+		/* The headers have, most likely, been written to the stream. The error is
 		 * occuring midway through template processing, which is writing to the response stream.
-		 * Server.go logs this; if we don't call the error handler below, then the stream is cut-off
-		 * with no other warning to the client, with this they at-least get the 500 template.
+		 * HttpError won't rewrite the header once written; if we don't call the error handler
+		 * below, then the stream is cut-off with no other warning to the client, with this they
+		 * at-least get the 500 template.
 		 */
 		ctx.HttpError(500)
 	}
@@ -127,11 +131,18 @@ func empty(o interface{}) bool {
 	}
 	return true
 }
+func url(name string, params ...interface{}) (string, error) {
+	if URLServer == nil {
+		return "", errors.New("URLServer hasn't been set")
+	}
+	return URLServer.URL(name, params...)
+}
 
 var commonFuncs = template.FuncMap{
 	"equals": equals,
 	"join":   strings.Join,
 	"empty":  empty,
+	"url":    url,
 }
 
 // NewTmpl returns a new template
@@ -225,3 +236,32 @@ func (v *TemplateView) Execute(ctx dingo.Context, data interface{}) error {
 
 	return v.Tmpl.Execute(ctx.Response, data)
 }
+
+/*--------------------------------Error Views---------------------------------*/
+
+// ErrorData is the data passed to views rendered by an ErrorHandler.
+type ErrorData struct {
+	Status int
+	Text   string
+	Err    error
+}
+
+// ErrorHandler returns a `dingo.Error` rendering the view for the given key,
+// eg. `server.HandleError(404, views.ErrorHandler("404.html"))`.
+func ErrorHandler(key string) dingo.Error {
+	return func(ctx dingo.Context, status int) bool {
+		v := Get(key)
+		if v == nil {
+			return false
+		}
+
+		if !ctx.Written() {
+			ctx.Response.WriteHeader(status)
+		}
+		data := ErrorData{status, http.StatusText(status), ctx.Err}
+		if err := v.Execute(ctx, data); err != nil {
+			log.Println("dingo: error template execution error, ", err)
+		}
+		return true
+	}
+}
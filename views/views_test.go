@@ -76,7 +76,7 @@ func TestGet(t *testing.T) {
 	for _, d := range addData {
 		Add(d.name, d.view)
 		if v := Get(d.name); v != d.view {
-			t.Errorf("Failed to get(%s)")
+			t.Errorf("Failed to get(%s)", d.name)
 		}
 	}
 }